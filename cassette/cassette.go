@@ -0,0 +1,289 @@
+// Package cassette 录制及回放HTTP交互，用于在单元测试中确定性地重放真实接口的响应。
+//
+// Recorder实现了http.RoundTripper，可注入dingtalk.Client（dingtalk.WithHTTPClient）及dockerhub.Client（Option.HTTPClient）：
+//
+//	rec, err := cassette.New("testdata/get_user.json", cassette.ModeReplayOrRecord)
+//	defer rec.Stop()
+//	client := dingtalk.NewClient(opt, dingtalk.WithHTTPClient(rec.Client()))
+//
+// 录制时按Redactions清除access_token、appsecret、signature、Authorization等敏感信息后再落盘，
+// 回放时对实际请求执行同样的清除后再匹配，因此使用假凭证即可回放。
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// Mode 录制模式
+type Mode int
+
+const (
+	// ModeReplay 仅回放，请求未被录制时返回ErrInteractionNotFound
+	ModeReplay Mode = iota
+	// ModeRecord 发送真实请求并覆盖录制文件
+	ModeRecord
+	// ModeReplayOrRecord 录制文件存在时回放，否则录制
+	ModeReplayOrRecord
+)
+
+// ErrInteractionNotFound 回放模式下请求没有对应的录制记录
+var ErrInteractionNotFound = errors.New("cassette: interaction not found")
+
+type (
+	// Request 录制的请求
+	Request struct {
+		Method string      `json:"method"`
+		URL    string      `json:"url"`
+		Header http.Header `json:"header,omitempty"`
+		Body   Body        `json:"body,omitempty"`
+	}
+
+	// Response 录制的响应
+	Response struct {
+		StatusCode int         `json:"status_code"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       Body        `json:"body,omitempty"`
+	}
+
+	// Body 报文内容，非UTF-8内容以base64保存
+	Body []byte
+
+	// Interaction 一次请求及其响应
+	Interaction struct {
+		Request  *Request  `json:"request"`
+		Response *Response `json:"response"`
+	}
+
+	// Cassette 录制文件的内容
+	Cassette struct {
+		Interactions []*Interaction `json:"interactions"`
+	}
+
+	// Matcher 判断实际请求（已清除敏感信息）与录制的请求是否匹配
+	Matcher func(actual, recorded *Request) bool
+
+	// Option Recorder的配置项
+	Option func(*Recorder)
+
+	// Recorder 录制及回放HTTP交互的http.RoundTripper
+	Recorder struct {
+		mu         sync.Mutex
+		path       string
+		mode       Mode
+		recording  bool
+		transport  http.RoundTripper
+		redactions []Redaction
+		matcher    Matcher
+		cassette   *Cassette
+		used       []bool
+	}
+)
+
+// WithTransport 录制时实际发送请求的http.RoundTripper，默认为http.DefaultTransport
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithRedactions 替换默认的敏感信息清除规则
+func WithRedactions(redactions ...Redaction) Option {
+	return func(r *Recorder) {
+		r.redactions = redactions
+	}
+}
+
+// WithMatcher 替换默认的请求匹配规则，默认要求method、URL及body完全一致
+func WithMatcher(matcher Matcher) Option {
+	return func(r *Recorder) {
+		r.matcher = matcher
+	}
+}
+
+// DefaultMatcher 默认的请求匹配规则
+func DefaultMatcher(actual, recorded *Request) bool {
+	return actual.Method == recorded.Method && actual.URL == recorded.URL && bytes.Equal(actual.Body, recorded.Body)
+}
+
+// New 创建Recorder，回放模式下加载录制文件，录制模式下需调用Stop保存
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:       path,
+		mode:       mode,
+		transport:  http.DefaultTransport,
+		redactions: DefaultRedactions(),
+		matcher:    DefaultMatcher,
+		cassette:   new(Cassette),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	switch mode {
+	case ModeRecord:
+		r.recording = true
+	case ModeReplay, ModeReplayOrRecord:
+		data, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			if err = json.Unmarshal(data, r.cassette); err != nil {
+				return nil, fmt.Errorf("cassette: load %s: %w", path, err)
+			}
+			r.used = make([]bool, len(r.cassette.Interactions))
+		case os.IsNotExist(err) && mode == ModeReplayOrRecord:
+			r.recording = true
+		default:
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cassette: unknown mode %d", mode)
+	}
+	return r, nil
+}
+
+// Recording 是否处于录制状态
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// Client 返回使用该Recorder发送请求的http.Client
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Interactions 返回已录制或已加载的交互
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Interaction(nil), r.cassette.Interactions...)
+}
+
+// RoundTrip 实现http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := &Request{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone(), Body: body}
+	for _, redaction := range r.redactions {
+		redaction.redactRequest(recorded)
+	}
+
+	if !r.recording {
+		return r.replay(req, recorded)
+	}
+
+	res, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	response := &Response{StatusCode: res.StatusCode, Header: res.Header.Clone(), Body: append(Body(nil), data...)}
+	for _, redaction := range r.redactions {
+		redaction.redactResponse(response)
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{Request: recorded, Response: response})
+	r.mu.Unlock()
+	return res, nil
+}
+
+// replay 返回第一个未被使用且匹配的录制响应，同一请求多次录制时按录制顺序依次回放
+func (r *Recorder) replay(req *http.Request, actual *Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.matcher(actual, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+		res := interaction.Response
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)),
+			StatusCode:    res.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        res.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader(res.Body)),
+			ContentLength: int64(len(res.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, actual.Method, actual.URL)
+}
+
+// Stop 录制状态下将交互保存至录制文件，回放状态下不做任何操作
+func (r *Recorder) Stop() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append(data, '\n'), 0644)
+}
+
+// MarshalJSON UTF-8内容以字符串保存便于阅读及比对，其余以base64保存
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON 见MarshalJSON
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	if err != nil {
+		return err
+	}
+	*b = raw
+	return nil
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
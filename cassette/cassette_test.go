@@ -0,0 +1,102 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wosai/go-clients/dingtalk"
+	"github.com/wosai/go-clients/dingtalk/dingtalktest"
+)
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dingtalk.json")
+	opt := dingtalk.Option{AppKey: dingtalktest.AppKey, AppSecret: dingtalktest.AppSecret}
+
+	fake := dingtalktest.NewServer()
+	fake.AddUsers(dingtalktest.User{UserID: "manager", Name: "张三"})
+	rec, err := New(path, ModeReplayOrRecord)
+	assert.Nil(t, err)
+	assert.True(t, rec.Recording())
+
+	client := dingtalk.NewClient(opt, dingtalk.WithBaseURL(fake.URL), dingtalk.WithHTTPClient(rec.Client()))
+	user, _, err := client.GetUserInfoV2(context.Background(), &dingtalk.RequestUserGet{UserID: "manager"})
+	assert.Nil(t, err)
+	assert.Equal(t, "张三", user.Result.Name)
+	assert.Nil(t, rec.Stop())
+	fake.Close()
+
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), dingtalktest.AppSecret)
+	assert.NotContains(t, string(data), "token-1")
+	assert.Contains(t, string(data), Redacted)
+
+	// 回放时无需真实服务端及凭证
+	rec, err = New(path, ModeReplay)
+	assert.Nil(t, err)
+	assert.False(t, rec.Recording())
+	client = dingtalk.NewClient(dingtalk.Option{AppKey: dingtalktest.AppKey, AppSecret: "other"}, dingtalk.WithBaseURL(fake.URL), dingtalk.WithHTTPClient(rec.Client()))
+	user, _, err = client.GetUserInfoV2(context.Background(), &dingtalk.RequestUserGet{UserID: "manager"})
+	assert.Nil(t, err)
+	assert.Equal(t, "张三", user.Result.Name)
+
+	_, _, err = client.GetUserInfoV2(context.Background(), &dingtalk.RequestUserGet{UserID: "nobody"})
+	assert.True(t, errors.Is(err, ErrInteractionNotFound))
+}
+
+func TestRedaction(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Authorization", "Bearer secret")
+		_, _ = w.Write([]byte(`{"data":[{"signature":"secret","name":"ok"}]}`))
+	}))
+	defer srv.Close()
+
+	rec, err := New(filepath.Join(t.TempDir(), "redact.json"), ModeRecord, WithRedactions(append(DefaultRedactions(), Redaction{Key: "otp", Replacement: "***"})...))
+	assert.Nil(t, err)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/login?Signature=secret&page=1", strings.NewReader(`{"user":"admin","Password":"secret","OTP":"123456"}`))
+	req.Header.Set("Authorization", "Basic secret")
+	res, err := rec.Client().Do(req)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(res.Body)
+	assert.Contains(t, string(body), "secret", "调用方收到的响应不受影响")
+
+	interaction := rec.Interactions()[0]
+	// query参数及JSON字段名不区分大小写
+	assert.Equal(t, srv.URL+"/login?Signature=REDACTED&page=1", interaction.Request.URL)
+	assert.Equal(t, Redacted, interaction.Request.Header.Get("Authorization"))
+	assert.JSONEq(t, `{"user":"admin","Password":"REDACTED","OTP":"***"}`, string(interaction.Request.Body))
+	assert.Equal(t, Redacted, interaction.Response.Header.Get("Authorization"))
+	assert.JSONEq(t, `{"data":[{"signature":"REDACTED","name":"ok"}]}`, string(interaction.Response.Body))
+}
+
+func TestRecorder_SuiteAccessToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suite.json")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","suite_access_token":"suite-token-1","expires_in":7200}`))
+	}))
+	defer srv.Close()
+
+	rec, err := New(path, ModeRecord)
+	assert.Nil(t, err)
+	suite, err := dingtalk.NewSuiteClient(dingtalk.SuiteOption{SuiteKey: "suite-key", SuiteSecret: "suite-secret-1"}, nil, dingtalk.WithBaseURL(srv.URL), dingtalk.WithHTTPClient(rec.Client()))
+	assert.Nil(t, err)
+	assert.Nil(t, suite.SetSuiteTicket(context.Background(), "suite-ticket-1"))
+	token, _, err := suite.GetSuiteAccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "suite-token-1", token)
+	assert.Nil(t, rec.Stop())
+
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "suite-key")
+	assert.NotContains(t, string(data), "suite-secret-1")
+	assert.NotContains(t, string(data), "suite-ticket-1")
+	assert.NotContains(t, string(data), "suite-token-1")
+}
@@ -0,0 +1,112 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted 默认的替换值
+const Redacted = "REDACTED"
+
+// Redaction 敏感信息清除规则：Key按名称匹配query参数、header及JSON报文中任意层级的字段，均不区分大小写
+type Redaction struct {
+	Key         string
+	Replacement string // 为空时使用Redacted
+}
+
+// DefaultRedactions 默认清除钉钉及镜像仓库接口中的凭证
+func DefaultRedactions() []Redaction {
+	return []Redaction{
+		{Key: "access_token"},
+		{Key: "suite_access_token"},
+		{Key: "token"},
+		{Key: "appsecret"},
+		{Key: "suite_secret"},
+		{Key: "suite_ticket"},
+		{Key: "suiteTicket"},
+		{Key: "permanent_code"},
+		{Key: "password"},
+		{Key: "signature"},
+		{Key: "Authorization"},
+		{Key: "x-acs-dingtalk-access-token"},
+	}
+}
+
+func (rd Redaction) replacement() string {
+	if rd.Replacement == "" {
+		return Redacted
+	}
+	return rd.Replacement
+}
+
+func (rd Redaction) redactRequest(req *Request) {
+	if u, err := url.Parse(req.URL); err == nil {
+		query := u.Query()
+		redacted := false
+		for k := range query {
+			if strings.EqualFold(k, rd.Key) {
+				query.Set(k, rd.replacement())
+				redacted = true
+			}
+		}
+		if redacted {
+			u.RawQuery = query.Encode()
+			req.URL = u.String()
+		}
+	}
+	rd.redactHeader(req.Header)
+	req.Body = rd.redactJSON(req.Body)
+}
+
+func (rd Redaction) redactResponse(res *Response) {
+	rd.redactHeader(res.Header)
+	res.Body = rd.redactJSON(res.Body)
+}
+
+func (rd Redaction) redactHeader(header http.Header) {
+	key := http.CanonicalHeaderKey(rd.Key)
+	if _, ok := header[key]; ok {
+		header.Set(key, rd.replacement())
+	}
+}
+
+// redactJSON 非JSON报文或未包含Key时原样返回
+func (rd Redaction) redactJSON(body Body) Body {
+	if len(body) == 0 {
+		return body
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v interface{}
+	if decoder.Decode(&v) != nil || !rd.redactValue(v) {
+		return body
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return data
+}
+
+func (rd Redaction) redactValue(v interface{}) bool {
+	redacted := false
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if strings.EqualFold(k, rd.Key) {
+				val[k] = rd.replacement()
+				redacted = true
+				continue
+			}
+			redacted = rd.redactValue(child) || redacted
+		}
+	case []interface{}:
+		for _, child := range val {
+			redacted = rd.redactValue(child) || redacted
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,336 @@
+package dingtalk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jacexh/requests"
+)
+
+type (
+	// AttendanceRecord 打卡详情
+	AttendanceRecord struct {
+		ID             int64         `json:"id"`
+		UserID         string        `json:"userId"`
+		GroupID        int64         `json:"groupId"`
+		PlanID         int64         `json:"planId"`
+		ApproveID      int64         `json:"approveId,omitempty"`
+		ProcInstID     string        `json:"procInstId,omitempty"`
+		CheckType      string        `json:"checkType"`      // OnDuty上班 OffDuty下班
+		SourceType     string        `json:"sourceType"`     // ATM考勤机 BEACON DING_ATM APPROVE审批 USER用户打卡 SYSTEM系统自动打卡等
+		TimeResult     string        `json:"timeResult"`     // Normal正常 Early早退 Late迟到 SeriousLate严重迟到 Absenteeism旷工迟到 NotSigned未打卡
+		LocationResult string        `json:"locationResult"` // Normal范围内 Outside范围外 NotSigned未打卡
+		WorkDate       UnixTimestamp `json:"workDate"`
+		BaseCheckTime  UnixTimestamp `json:"baseCheckTime"`
+		UserCheckTime  UnixTimestamp `json:"userCheckTime"`
+		DeviceID       string        `json:"deviceId,omitempty"`
+		UserAddress    string        `json:"userAddress,omitempty"`
+		UserLongitude  float64       `json:"userLongitude,omitempty"`
+		UserLatitude   float64       `json:"userLatitude,omitempty"`
+		OutsideRemark  string        `json:"outsideRemark,omitempty"`
+		IsLegal        string        `json:"isLegal,omitempty"`
+	}
+
+	// RequestListAttendanceRecords https://oapi.dingtalk.com/attendance/listRecord
+	RequestListAttendanceRecords struct {
+		UserIDs       []string `json:"userIds"`
+		CheckDateFrom string   `json:"checkDateFrom"`
+		CheckDateTo   string   `json:"checkDateTo"`
+		IsI18n        bool     `json:"isI18n,omitempty"`
+	}
+
+	// ResponseListAttendanceRecords https://oapi.dingtalk.com/attendance/listRecord
+	ResponseListAttendanceRecords struct {
+		*DingtalkErr `json:",inline"`
+		RecordResult []*AttendanceRecord `json:"recordresult"`
+	}
+
+	// AttendanceResult 打卡结果
+	AttendanceResult struct {
+		ID             int64         `json:"id"`
+		UserID         string        `json:"userId"`
+		GroupID        int64         `json:"groupId"`
+		PlanID         int64         `json:"planId"`
+		RecordID       int64         `json:"recordId"`
+		ApproveID      int64         `json:"approveId,omitempty"`
+		ProcInstID     string        `json:"procInstId,omitempty"`
+		CheckType      string        `json:"checkType"`
+		SourceType     string        `json:"sourceType"`
+		TimeResult     string        `json:"timeResult"`
+		LocationResult string        `json:"locationResult"`
+		WorkDate       UnixTimestamp `json:"workDate"`
+		BaseCheckTime  UnixTimestamp `json:"baseCheckTime"`
+		UserCheckTime  UnixTimestamp `json:"userCheckTime"`
+	}
+
+	// RequestListAttendanceResults https://oapi.dingtalk.com/attendance/list ，时间跨度不超过7天，UserIDs不超过50个
+	RequestListAttendanceResults struct {
+		WorkDateFrom time.Time
+		WorkDateTo   time.Time
+		UserIDs      []string
+		Offset       int
+		Limit        int // 最大50，小于等于0时使用50
+	}
+
+	AttendanceResultPage struct {
+		Results []*AttendanceResult `json:"recordresult"`
+		HasMore bool                `json:"hasMore"`
+	}
+
+	// ResponseListAttendanceResults https://oapi.dingtalk.com/attendance/list
+	ResponseListAttendanceResults struct {
+		*DingtalkErr          `json:",inline"`
+		*AttendanceResultPage `json:",inline"`
+	}
+
+	AttendanceCheckTime struct {
+		CheckType string `json:"check_type"`
+		Across    int    `json:"across"` // 0当天 1次日
+		CheckTime string `json:"check_time"`
+	}
+
+	AttendanceSection struct {
+		Times []*AttendanceCheckTime `json:"times"`
+	}
+
+	AttendanceClass struct {
+		ClassID   int64                `json:"class_id"`
+		ClassName string               `json:"class_name"`
+		Sections  []*AttendanceSection `json:"sections"`
+	}
+
+	// AttendanceGroup 考勤组
+	AttendanceGroup struct {
+		ID      int64              `json:"group_id"`
+		Name    string             `json:"name"`
+		Type    string             `json:"type"` // FIXED固定班制 TURN排班制 NONE自由工时
+		Classes []*AttendanceClass `json:"classes"`
+	}
+
+	// ResponseGetUserAttendanceGroup https://oapi.dingtalk.com/topapi/attendance/getusergroup
+	ResponseGetUserAttendanceGroup struct {
+		BasicResponse `json:",inline"`
+		Result        *AttendanceGroup `json:"result"`
+	}
+
+	// RequestLeaveStatus https://oapi.dingtalk.com/topapi/attendance/getleavestatus
+	RequestLeaveStatus struct {
+		UserIDs   []string
+		StartTime time.Time
+		EndTime   time.Time
+		Offset    int
+		Size      int // 最大20
+	}
+
+	LeaveStatus struct {
+		UserID          string        `json:"userid"`
+		StartTime       UnixTimestamp `json:"start_time"`
+		EndTime         UnixTimestamp `json:"end_time"`
+		DurationPercent int           `json:"duration_percent"` // 假期时长*100
+		DurationUnit    string        `json:"duration_unit"`    // percent_day天 percent_hour小时
+	}
+
+	LeaveStatusPage struct {
+		HasMore     bool           `json:"has_more"`
+		LeaveStatus []*LeaveStatus `json:"leave_status"`
+	}
+
+	// ResponseLeaveStatus https://oapi.dingtalk.com/topapi/attendance/getleavestatus
+	ResponseLeaveStatus struct {
+		BasicResponse `json:",inline"`
+		Result        *LeaveStatusPage `json:"result"`
+	}
+)
+
+const (
+	// AttendanceMaxRange 考勤接口单次查询的最大时间跨度
+	AttendanceMaxRange = 7 * 24 * time.Hour
+	// AttendanceMaxUsers 考勤接口单次查询的最大用户数
+	AttendanceMaxUsers = 50
+
+	attendanceTimeLayout = "2006-01-02 15:04:05"
+)
+
+// attendanceLocation 考勤接口中的日期字符串按北京时间解析
+var attendanceLocation = time.FixedZone("CST", 8*60*60)
+
+// ListAttendanceRecords 获取打卡详情，超过7天的时间窗口及超过50人的用户列表会被自动拆分为多次请求 https://developers.dingtalk.com/document/app/attendance-clock-in-record-is-open
+func (ding *Client) ListAttendanceRecords(ctx context.Context, userIDs []string, from, to time.Time) ([]*AttendanceRecord, *http.Response, error) {
+	var records []*AttendanceRecord
+	var res *http.Response
+
+	for _, users := range splitUserIDs(userIDs, AttendanceMaxUsers) {
+		for _, window := range splitTimeRange(from, to, AttendanceMaxRange) {
+			ret := new(ResponseListAttendanceRecords)
+			req := &RequestListAttendanceRecords{
+				UserIDs:       users,
+				CheckDateFrom: window[0].In(attendanceLocation).Format(attendanceTimeLayout),
+				CheckDateTo:   window[1].In(attendanceLocation).Format(attendanceTimeLayout),
+			}
+			var err error
+			err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+				res, _, err = ding.post(
+					ctx,
+					ding.url+"/attendance/listRecord",
+					requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: req},
+					UnmarshalAndParseError(ret),
+				)
+				return err
+			})
+			if err != nil {
+				return records, res, err
+			}
+			records = append(records, ret.RecordResult...)
+		}
+	}
+	return records, res, nil
+}
+
+// ListAttendanceResults 分页获取打卡结果，时间跨度超过7天或用户超过50个时返回ErrInvalidParam，
+// 需要自动拆分时使用ListAllAttendanceResults https://developers.dingtalk.com/document/app/open-attendance-clock-in-data
+func (ding *Client) ListAttendanceResults(ctx context.Context, req *RequestListAttendanceResults) (*AttendanceResultPage, *http.Response, error) {
+	if err := req.validate(); err != nil {
+		return nil, nil, err
+	}
+	ret := new(ResponseListAttendanceResults)
+	var res *http.Response
+	var err error
+
+	limit := req.Limit
+	if limit <= 0 {
+		// limit为0时钉钉返回参数错误或空页
+		limit = AttendanceMaxUsers
+	}
+	body := map[string]interface{}{
+		"workDateFrom": req.WorkDateFrom.In(attendanceLocation).Format(attendanceTimeLayout),
+		"workDateTo":   req.WorkDateTo.In(attendanceLocation).Format(attendanceTimeLayout),
+		"userIdList":   req.UserIDs,
+		"offset":       req.Offset,
+		"limit":        limit,
+	}
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.url+"/attendance/list",
+			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: body},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return ret.AttendanceResultPage, res, err
+}
+
+// ListAllAttendanceResults 获取全部打卡结果，超过7天的时间窗口及超过50人的用户列表会被自动拆分，并遍历所有分页
+func (ding *Client) ListAllAttendanceResults(ctx context.Context, userIDs []string, from, to time.Time) ([]*AttendanceResult, *http.Response, error) {
+	var results []*AttendanceResult
+	var res *http.Response
+
+	for _, users := range splitUserIDs(userIDs, AttendanceMaxUsers) {
+		for _, window := range splitTimeRange(from, to, AttendanceMaxRange) {
+			req := &RequestListAttendanceResults{WorkDateFrom: window[0], WorkDateTo: window[1], UserIDs: users, Limit: AttendanceMaxUsers}
+			for {
+				page, raw, err := ding.ListAttendanceResults(ctx, req)
+				res = raw
+				if err != nil {
+					return results, res, err
+				}
+				if page == nil {
+					break
+				}
+				results = append(results, page.Results...)
+				if !page.HasMore || len(page.Results) == 0 {
+					break
+				}
+				req.Offset += len(page.Results)
+			}
+		}
+	}
+	return results, res, nil
+}
+
+// GetUserAttendanceGroup 获取用户考勤组 https://developers.dingtalk.com/document/app/queries-the-attendance-group-of-a-user
+func (ding *Client) GetUserAttendanceGroup(ctx context.Context, userID string) (*AttendanceGroup, *http.Response, error) {
+	ret := new(ResponseGetUserAttendanceGroup)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.url+"/topapi/attendance/getusergroup",
+			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: requests.Any{"userid": userID}},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return ret.Result, res, err
+}
+
+// GetLeaveStatus 查询请假状态 https://developers.dingtalk.com/document/app/query-status-of-leave
+func (ding *Client) GetLeaveStatus(ctx context.Context, req *RequestLeaveStatus) (*LeaveStatusPage, *http.Response, error) {
+	ret := new(ResponseLeaveStatus)
+	var res *http.Response
+	var err error
+
+	body := map[string]interface{}{
+		"userid_list": strings.Join(req.UserIDs, ","),
+		"start_time":  req.StartTime.UnixNano() / 1e6,
+		"end_time":    req.EndTime.UnixNano() / 1e6,
+		"offset":      req.Offset,
+		"size":        req.Size,
+	}
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.url+"/topapi/attendance/getleavestatus",
+			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: body},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return ret.Result, res, err
+}
+
+func (req *RequestListAttendanceResults) validate() error {
+	switch {
+	case req.WorkDateTo.Before(req.WorkDateFrom):
+		return fmt.Errorf("%w: workDateTo is before workDateFrom", ErrInvalidParam)
+	case req.WorkDateTo.Sub(req.WorkDateFrom) > AttendanceMaxRange:
+		return fmt.Errorf("%w: work date range exceeds %s", ErrInvalidParam, AttendanceMaxRange)
+	case len(req.UserIDs) == 0 || len(req.UserIDs) > AttendanceMaxUsers:
+		return fmt.Errorf("%w: userIdList must contain 1 to %d users", ErrInvalidParam, AttendanceMaxUsers)
+	case req.Limit > AttendanceMaxUsers:
+		return fmt.Errorf("%w: limit exceeds %d", ErrInvalidParam, AttendanceMaxUsers)
+	}
+	return nil
+}
+
+// splitUserIDs 按size切分用户列表
+func splitUserIDs(userIDs []string, size int) [][]string {
+	var batches [][]string
+	for len(userIDs) > size {
+		batches = append(batches, userIDs[:size])
+		userIDs = userIDs[size:]
+	}
+	if len(userIDs) > 0 {
+		batches = append(batches, userIDs)
+	}
+	return batches
+}
+
+// splitTimeRange 将[from, to]切分为跨度不超过max的闭区间，相邻区间间隔1秒以避免重复
+func splitTimeRange(from, to time.Time, max time.Duration) [][2]time.Time {
+	var windows [][2]time.Time
+	for !from.After(to) {
+		end := from.Add(max)
+		if end.After(to) {
+			end = to
+		}
+		windows = append(windows, [2]time.Time{from, end})
+		from = end.Add(time.Second)
+	}
+	return windows
+}
@@ -0,0 +1,83 @@
+package dingtalk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitTimeRange(t *testing.T) {
+	from := time.Date(2021, 3, 1, 0, 0, 0, 0, attendanceLocation)
+	to := time.Date(2021, 3, 20, 0, 0, 0, 0, attendanceLocation)
+	windows := splitTimeRange(from, to, AttendanceMaxRange)
+	assert.Len(t, windows, 3)
+	assert.Equal(t, from, windows[0][0])
+	assert.Equal(t, to, windows[2][1])
+	for _, w := range windows {
+		assert.True(t, w[1].Sub(w[0]) <= AttendanceMaxRange)
+	}
+	assert.Len(t, splitTimeRange(to, from, AttendanceMaxRange), 0)
+}
+
+func TestSplitUserIDs(t *testing.T) {
+	users := make([]string, 120)
+	batches := splitUserIDs(users, AttendanceMaxUsers)
+	assert.Len(t, batches, 3)
+	assert.Len(t, batches[2], 20)
+}
+
+func TestClient_ListAttendanceResults(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []map[string]interface{}
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		// 每个窗口返回两页
+		if body["offset"].(float64) == 0 {
+			_, _ = w.Write([]byte(`{"errcode":0,"hasMore":true,"recordresult":[{"id":1,"userId":"u"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"hasMore":false,"recordresult":[{"id":2,"userId":"u"}]}`))
+	})
+	defer closeFn()
+	ding.SetAccessToken("token")
+	ctx := context.Background()
+	from := time.Date(2021, 3, 1, 0, 0, 0, 0, attendanceLocation)
+	to := time.Date(2021, 3, 20, 0, 0, 0, 0, attendanceLocation)
+
+	_, _, err := ding.ListAttendanceResults(ctx, &RequestListAttendanceResults{WorkDateFrom: from, WorkDateTo: to, UserIDs: []string{"u"}})
+	assert.True(t, IsInvalidParam(err))
+	_, _, err = ding.ListAttendanceResults(ctx, &RequestListAttendanceResults{WorkDateFrom: from, WorkDateTo: from, UserIDs: make([]string, 51)})
+	assert.True(t, IsInvalidParam(err))
+	assert.Len(t, bodies, 0)
+
+	page, _, err := ding.ListAttendanceResults(ctx, &RequestListAttendanceResults{WorkDateFrom: from, WorkDateTo: from.Add(AttendanceMaxRange), UserIDs: []string{"u"}, Limit: 50})
+	assert.Nil(t, err)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, "2021-03-08 00:00:00", bodies[0]["workDateTo"])
+	assert.EqualValues(t, 50, bodies[0]["limit"])
+
+	// 未指定limit时使用最大值
+	_, _, err = ding.ListAttendanceResults(ctx, &RequestListAttendanceResults{WorkDateFrom: from, WorkDateTo: from, UserIDs: []string{"u"}})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 50, bodies[1]["limit"])
+
+	bodies = nil
+	users := make([]string, 60)
+	results, _, err := ding.ListAllAttendanceResults(ctx, users, from, to)
+	assert.Nil(t, err)
+	// 2批用户 * 3个时间窗口 * 2页
+	assert.Len(t, bodies, 12)
+	assert.Len(t, results, 12)
+	assert.Len(t, bodies[0]["userIdList"], 50)
+	assert.EqualValues(t, 1, bodies[1]["offset"])
+	assert.Equal(t, "2021-03-20 00:00:00", bodies[5]["workDateTo"])
+}
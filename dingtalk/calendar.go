@@ -0,0 +1,423 @@
+package dingtalk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jacexh/requests"
+)
+
+type (
+	// RecurrencePatternType 日程循环规则类型
+	RecurrencePatternType string
+
+	// RecurrenceRangeType 日程循环的结束方式
+	RecurrenceRangeType string
+
+	// RecurrencePattern 日程循环规则
+	RecurrencePattern struct {
+		Type       RecurrencePatternType `json:"type"`
+		DayOfMonth int                   `json:"dayOfMonth,omitempty"` // absoluteMonthly/absoluteYearly时有效
+		DaysOfWeek string                `json:"daysOfWeek,omitempty"` // 逗号分隔，如"monday,friday"
+		Index      string                `json:"index,omitempty"`      // relativeMonthly/relativeYearly时有效：first second third fourth last
+		Interval   int                   `json:"interval,omitempty"`   // 循环间隔
+	}
+
+	// RecurrenceRange 日程循环范围
+	RecurrenceRange struct {
+		Type                RecurrenceRangeType `json:"type"`
+		EndDate             *time.Time          `json:"endDate,omitempty"`
+		NumberOfOccurrences int                 `json:"numberOfOccurrences,omitempty"`
+	}
+
+	// Recurrence 日程循环规则
+	Recurrence struct {
+		Pattern *RecurrencePattern `json:"pattern"`
+		Range   *RecurrenceRange   `json:"range"`
+	}
+
+	// EventDateTime 日程时间，全天日程使用Date（yyyy-MM-dd），非全天日程使用DateTime
+	EventDateTime struct {
+		Date     string     `json:"date,omitempty"`
+		DateTime *time.Time `json:"dateTime,omitempty"`
+		TimeZone string     `json:"timeZone,omitempty"`
+	}
+
+	// EventAttendee 日程参与人，ID为unionId
+	EventAttendee struct {
+		ID             string `json:"id"`
+		DisplayName    string `json:"displayName,omitempty"`
+		ResponseStatus string `json:"responseStatus,omitempty"` // needsAction declined tentative accepted
+		IsOptional     bool   `json:"isOptional,omitempty"`
+		Self           bool   `json:"self,omitempty"`
+	}
+
+	EventLocation struct {
+		DisplayName  string   `json:"displayName,omitempty"`
+		MeetingRooms []string `json:"meetingRooms,omitempty"`
+	}
+
+	EventReminder struct {
+		Method  string `json:"method"` // dingtalk
+		Minutes int    `json:"minutes"`
+	}
+
+	EventOnlineMeetingInfo struct {
+		Type         string `json:"type"` // dingtalk
+		ConferenceID string `json:"conferenceId,omitempty"`
+		URL          string `json:"url,omitempty"`
+	}
+
+	EventMeetingRoom struct {
+		RoomID         string `json:"roomId"`
+		DisplayName    string `json:"displayName,omitempty"`
+		ResponseStatus string `json:"responseStatus,omitempty"`
+	}
+
+	// CalendarEvent 日程
+	CalendarEvent struct {
+		ID                string                  `json:"id,omitempty"`
+		Summary           string                  `json:"summary,omitempty"`
+		Description       string                  `json:"description,omitempty"`
+		Start             *EventDateTime          `json:"start,omitempty"`
+		End               *EventDateTime          `json:"end,omitempty"`
+		IsAllDay          bool                    `json:"isAllDay,omitempty"`
+		Recurrence        *Recurrence             `json:"recurrence,omitempty"`
+		Attendees         []*EventAttendee        `json:"attendees,omitempty"`
+		Organizer         *EventAttendee          `json:"organizer,omitempty"`
+		Location          *EventLocation          `json:"location,omitempty"`
+		Reminders         []*EventReminder        `json:"reminders,omitempty"`
+		OnlineMeetingInfo *EventOnlineMeetingInfo `json:"onlineMeetingInfo,omitempty"`
+		MeetingRooms      []*EventMeetingRoom     `json:"meetingRooms,omitempty"`
+		Status            string                  `json:"status,omitempty"` // confirmed cancelled
+		SeriesMasterID    string                  `json:"seriesMasterId,omitempty"`
+		Extra             map[string]string       `json:"extra,omitempty"`
+		CreateTime        string                  `json:"createTime,omitempty"`
+		UpdateTime        string                  `json:"updateTime,omitempty"`
+	}
+
+	// RequestListCalendarEvents 查询日程列表的条件
+	RequestListCalendarEvents struct {
+		TimeMin     time.Time
+		TimeMax     time.Time
+		ShowDeleted bool
+		MaxResults  int
+		NextToken   string
+		SyncToken   string
+	}
+
+	// CalendarEventPage 日程列表
+	CalendarEventPage struct {
+		Events    []*CalendarEvent `json:"events"`
+		NextToken string           `json:"nextToken,omitempty"`
+		SyncToken string           `json:"syncToken,omitempty"`
+	}
+
+	// RequestQuerySchedule 查询闲忙状态，UserIDs为unionId
+	RequestQuerySchedule struct {
+		UserIDs   []string  `json:"userIds"`
+		StartTime time.Time `json:"startTime"`
+		EndTime   time.Time `json:"endTime"`
+	}
+
+	ScheduleItem struct {
+		Status string         `json:"status"` // BUSY TENTATIVE
+		Start  *EventDateTime `json:"start"`
+		End    *EventDateTime `json:"end"`
+	}
+
+	// ScheduleInformation 用户的闲忙信息
+	ScheduleInformation struct {
+		UserID        string          `json:"userId"`
+		Error         string          `json:"error,omitempty"`
+		ScheduleItems []*ScheduleItem `json:"scheduleItems"`
+	}
+
+	ResponseQuerySchedule struct {
+		ScheduleInformation []*ScheduleInformation `json:"scheduleInformation"`
+	}
+
+	// RequestQueryMeetingRoomSchedule 查询会议室闲忙状态
+	RequestQueryMeetingRoomSchedule struct {
+		RoomIDs   []string  `json:"roomIds"`
+		StartTime time.Time `json:"startTime"`
+		EndTime   time.Time `json:"endTime"`
+	}
+
+	// MeetingRoomScheduleInformation 会议室的闲忙信息
+	MeetingRoomScheduleInformation struct {
+		RoomID        string          `json:"roomId"`
+		Error         string          `json:"error,omitempty"`
+		ScheduleItems []*ScheduleItem `json:"scheduleItems"`
+	}
+
+	ResponseQueryMeetingRoomSchedule struct {
+		ScheduleInformation []*MeetingRoomScheduleInformation `json:"scheduleInformation"`
+	}
+)
+
+const (
+	RecurrenceDaily           RecurrencePatternType = "daily"
+	RecurrenceWeekly          RecurrencePatternType = "weekly"
+	RecurrenceAbsoluteMonthly RecurrencePatternType = "absoluteMonthly"
+	RecurrenceRelativeMonthly RecurrencePatternType = "relativeMonthly"
+	RecurrenceAbsoluteYearly  RecurrencePatternType = "absoluteYearly"
+	RecurrenceRelativeYearly  RecurrencePatternType = "relativeYearly"
+
+	RecurrenceEndDate  RecurrenceRangeType = "endDate"
+	RecurrenceNoEnd    RecurrenceRangeType = "noEnd"
+	RecurrenceNumbered RecurrenceRangeType = "numbered"
+
+	// PrimaryCalendar 用户的主日历
+	PrimaryCalendar = "primary"
+)
+
+// requireEventID 日程ID为空时请求会落到日程列表的地址上，提前返回错误
+func requireEventID(eventID string) error {
+	if eventID == "" {
+		return fmt.Errorf("%w: event id is required", ErrInvalidParam)
+	}
+	return nil
+}
+
+func (ding *Client) calendarURL(unionID string, segments ...string) string {
+	u := ding.api + "/v1.0/calendar/users/" + url.PathEscape(unionID) + "/calendars/" + PrimaryCalendar + "/events"
+	for _, seg := range segments {
+		u += "/" + url.PathEscape(seg)
+	}
+	return u
+}
+
+// CreateCalendarEvent 创建日程，unionID为日程组织者 https://developers.dingtalk.com/document/app/create-event
+func (ding *Client) CreateCalendarEvent(ctx context.Context, unionID string, event *CalendarEvent) (*CalendarEvent, *http.Response, error) {
+	ret := new(CalendarEvent)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, nonIdempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.calendarURL(unionID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Json: event},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret, res, err
+}
+
+// UpdateCalendarEvent 修改日程，event.ID为空时返回ErrInvalidParam https://developers.dingtalk.com/document/app/modify-event
+func (ding *Client) UpdateCalendarEvent(ctx context.Context, unionID string, event *CalendarEvent) (*CalendarEvent, *http.Response, error) {
+	if err := requireEventID(event.ID); err != nil {
+		return nil, nil, err
+	}
+	ret := new(CalendarEvent)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.put(
+			ctx,
+			ding.calendarURL(unionID, event.ID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Json: event},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret, res, err
+}
+
+// DeleteCalendarEvent 删除日程 https://developers.dingtalk.com/document/app/delete-event
+func (ding *Client) DeleteCalendarEvent(ctx context.Context, unionID, eventID string) (*http.Response, error) {
+	if err := requireEventID(eventID); err != nil {
+		return nil, err
+	}
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.delete(
+			ctx,
+			ding.calendarURL(unionID, eventID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken())},
+			UnmarshalGatewayResponse(nil),
+		)
+		return err
+	})
+	return res, err
+}
+
+// GetCalendarEvent 查询单个日程详情 https://developers.dingtalk.com/document/app/queries-event-details
+func (ding *Client) GetCalendarEvent(ctx context.Context, unionID, eventID string) (*CalendarEvent, *http.Response, error) {
+	if err := requireEventID(eventID); err != nil {
+		return nil, nil, err
+	}
+	ret := new(CalendarEvent)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.get(
+			ctx,
+			ding.calendarURL(unionID, eventID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken())},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret, res, err
+}
+
+// ListCalendarEvents 查询日程列表 https://developers.dingtalk.com/document/app/query-event-list
+func (ding *Client) ListCalendarEvents(ctx context.Context, unionID string, req *RequestListCalendarEvents) (*CalendarEventPage, *http.Response, error) {
+	ret := new(CalendarEventPage)
+	var res *http.Response
+	var err error
+
+	query := requests.Any{}
+	if !req.TimeMin.IsZero() {
+		query["timeMin"] = req.TimeMin.Format(time.RFC3339)
+	}
+	if !req.TimeMax.IsZero() {
+		query["timeMax"] = req.TimeMax.Format(time.RFC3339)
+	}
+	if req.ShowDeleted {
+		query["showDeleted"] = "true"
+	}
+	if req.MaxResults > 0 {
+		query["maxResults"] = strconv.Itoa(req.MaxResults)
+	}
+	if req.NextToken != "" {
+		query["nextToken"] = req.NextToken
+	}
+	if req.SyncToken != "" {
+		query["syncToken"] = req.SyncToken
+	}
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.get(
+			ctx,
+			ding.calendarURL(unionID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Query: query},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret, res, err
+}
+
+// AddEventAttendees 新增日程参与人 https://developers.dingtalk.com/document/app/add-event-participant
+func (ding *Client) AddEventAttendees(ctx context.Context, unionID, eventID string, attendees []*EventAttendee) (*http.Response, error) {
+	if err := requireEventID(eventID); err != nil {
+		return nil, err
+	}
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, nonIdempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.calendarURL(unionID, eventID, "attendees"),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Json: map[string]interface{}{"attendeesToAdd": attendees}},
+			UnmarshalGatewayResponse(nil),
+		)
+		return err
+	})
+	return res, err
+}
+
+// RemoveEventAttendees 删除日程参与人，attendeeIDs为unionId https://developers.dingtalk.com/document/app/delete-event-participant
+func (ding *Client) RemoveEventAttendees(ctx context.Context, unionID, eventID string, attendeeIDs []string) (*http.Response, error) {
+	if err := requireEventID(eventID); err != nil {
+		return nil, err
+	}
+	var res *http.Response
+	var err error
+
+	attendees := make([]*EventAttendee, 0, len(attendeeIDs))
+	for _, id := range attendeeIDs {
+		attendees = append(attendees, &EventAttendee{ID: id})
+	}
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.calendarURL(unionID, eventID, "attendees", "batchRemove"),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Json: map[string]interface{}{"attendeesToRemove": attendees}},
+			UnmarshalGatewayResponse(nil),
+		)
+		return err
+	})
+	return res, err
+}
+
+// QuerySchedule 查询用户闲忙状态 https://developers.dingtalk.com/document/app/query-user-busy-status
+func (ding *Client) QuerySchedule(ctx context.Context, unionID string, req *RequestQuerySchedule) ([]*ScheduleInformation, *http.Response, error) {
+	ret := new(ResponseQuerySchedule)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.api+"/v1.0/calendar/users/"+url.PathEscape(unionID)+"/querySchedule",
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Json: req},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret.ScheduleInformation, res, err
+}
+
+// AddEventMeetingRooms 为日程预定会议室 https://developers.dingtalk.com/document/app/add-a-meeting-room
+func (ding *Client) AddEventMeetingRooms(ctx context.Context, unionID, eventID string, roomIDs []string) (*http.Response, error) {
+	return ding.changeEventMeetingRooms(ctx, unionID, eventID, roomIDs, nonIdempotent, "meetingRoomsToAdd", "meetingRooms")
+}
+
+// RemoveEventMeetingRooms 取消日程预定的会议室 https://developers.dingtalk.com/document/app/remove-a-meeting-room
+func (ding *Client) RemoveEventMeetingRooms(ctx context.Context, unionID, eventID string, roomIDs []string) (*http.Response, error) {
+	return ding.changeEventMeetingRooms(ctx, unionID, eventID, roomIDs, idempotent, "meetingRoomsToRemove", "meetingRooms", "batchRemove")
+}
+
+func (ding *Client) changeEventMeetingRooms(ctx context.Context, unionID, eventID string, roomIDs []string, isIdempotent bool, field string, segments ...string) (*http.Response, error) {
+	if err := requireEventID(eventID); err != nil {
+		return nil, err
+	}
+	var res *http.Response
+	var err error
+
+	rooms := make([]*EventMeetingRoom, 0, len(roomIDs))
+	for _, id := range roomIDs {
+		rooms = append(rooms, &EventMeetingRoom{RoomID: id})
+	}
+	err = ding.withRetry(ctx, isIdempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.calendarURL(unionID, append([]string{eventID}, segments...)...),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Json: map[string]interface{}{field: rooms}},
+			UnmarshalGatewayResponse(nil),
+		)
+		return err
+	})
+	return res, err
+}
+
+// QueryMeetingRoomSchedule 查询会议室闲忙状态 https://developers.dingtalk.com/document/app/query-meeting-room-busy-status
+func (ding *Client) QueryMeetingRoomSchedule(ctx context.Context, unionID string, req *RequestQueryMeetingRoomSchedule) ([]*MeetingRoomScheduleInformation, *http.Response, error) {
+	ret := new(ResponseQueryMeetingRoomSchedule)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.api+"/v1.0/calendar/users/"+url.PathEscape(unionID)+"/meetingRooms/schedules/query",
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Json: req},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret.ScheduleInformation, res, err
+}
@@ -0,0 +1,226 @@
+package dingtalk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gatewayRequest 新版服务端API收到的请求
+type gatewayRequest struct {
+	Method string
+	Path   string // 未解码的路径
+	Query  map[string]string
+	Token  string
+	Body   map[string]interface{}
+}
+
+// newGatewayTestClient 新版服务端API与gettoken使用同一个测试服务端，handler返回状态码及响应报文
+func newGatewayTestClient(handler func(req *gatewayRequest) (int, string)) (*Client, func() []*gatewayRequest, func()) {
+	var mu sync.Mutex
+	var received []*gatewayRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gettoken" {
+			_, _ = w.Write([]byte(`{"errcode":0,"access_token":"refreshed","expires_in":7200}`))
+			return
+		}
+		req := &gatewayRequest{Method: r.Method, Path: r.URL.EscapedPath(), Query: map[string]string{}, Token: r.Header.Get("x-acs-dingtalk-access-token")}
+		for k := range r.URL.Query() {
+			req.Query[k] = r.URL.Query().Get(k)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req.Body)
+		mu.Lock()
+		received = append(received, req)
+		mu.Unlock()
+
+		status, body := handler(req)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	ding := NewClient(Option{AppKey: "key", AppSecret: "secret"}, WithBaseURL(srv.URL), WithGatewayURL(srv.URL), WithRetryPolicy(fastRetry), WithRateLimiter(nil))
+	ding.SetAccessToken("token")
+	requests := func() []*gatewayRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*gatewayRequest(nil), received...)
+	}
+	return ding, requests, srv.Close
+}
+
+func TestClient_CreateCalendarEvent(t *testing.T) {
+	ding, received, closeFn := newGatewayTestClient(func(req *gatewayRequest) (int, string) {
+		return http.StatusOK, `{"id":"event-1","summary":"周会","recurrence":{"pattern":{"type":"weekly","daysOfWeek":"monday"},"range":{"type":"numbered","numberOfOccurrences":10}}}`
+	})
+	defer closeFn()
+
+	start := time.Date(2021, 6, 7, 10, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	end := start.Add(time.Hour)
+	until := start.AddDate(0, 3, 0)
+	event, _, err := ding.CreateCalendarEvent(context.Background(), "union/1", &CalendarEvent{
+		Summary: "周会",
+		Start:   &EventDateTime{DateTime: &start, TimeZone: "Asia/Shanghai"},
+		End:     &EventDateTime{DateTime: &end, TimeZone: "Asia/Shanghai"},
+		Recurrence: &Recurrence{
+			Pattern: &RecurrencePattern{Type: RecurrenceWeekly, DaysOfWeek: "monday,friday", Interval: 1},
+			Range:   &RecurrenceRange{Type: RecurrenceEndDate, EndDate: &until},
+		},
+		Attendees: []*EventAttendee{{ID: "union-2", IsOptional: true}, {ID: "union-3"}},
+		Reminders: []*EventReminder{{Method: "dingtalk", Minutes: 15}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "event-1", event.ID)
+	assert.Equal(t, RecurrenceNumbered, event.Recurrence.Range.Type)
+	assert.Equal(t, 10, event.Recurrence.Range.NumberOfOccurrences)
+
+	reqs := received()
+	assert.Len(t, reqs, 1)
+	req := reqs[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/v1.0/calendar/users/union%2F1/calendars/primary/events", req.Path)
+	assert.Equal(t, "token", req.Token)
+	assert.Equal(t, map[string]interface{}{
+		"pattern": map[string]interface{}{"type": "weekly", "daysOfWeek": "monday,friday", "interval": float64(1)},
+		"range":   map[string]interface{}{"type": "endDate", "endDate": "2021-09-07T10:00:00+08:00"},
+	}, req.Body["recurrence"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "union-2", "isOptional": true},
+		map[string]interface{}{"id": "union-3"},
+	}, req.Body["attendees"])
+	assert.Equal(t, map[string]interface{}{"dateTime": "2021-06-07T10:00:00+08:00", "timeZone": "Asia/Shanghai"}, req.Body["start"])
+	assert.NotContains(t, req.Body, "id")
+	assert.NotContains(t, req.Body, "isAllDay")
+}
+
+func TestClient_CalendarEventURLs(t *testing.T) {
+	ding, received, closeFn := newGatewayTestClient(func(req *gatewayRequest) (int, string) {
+		return http.StatusOK, `{}`
+	})
+	defer closeFn()
+	ctx := context.Background()
+
+	_, _, err := ding.UpdateCalendarEvent(ctx, "union", &CalendarEvent{ID: "event-1", Summary: "改期"})
+	assert.Nil(t, err)
+	_, _, err = ding.GetCalendarEvent(ctx, "union", "event-1")
+	assert.Nil(t, err)
+	_, err = ding.DeleteCalendarEvent(ctx, "union", "event-1")
+	assert.Nil(t, err)
+	_, err = ding.AddEventAttendees(ctx, "union", "event-1", []*EventAttendee{{ID: "union-2"}})
+	assert.Nil(t, err)
+	_, err = ding.RemoveEventAttendees(ctx, "union", "event-1", []string{"union-2"})
+	assert.Nil(t, err)
+	_, err = ding.AddEventMeetingRooms(ctx, "union", "event-1", []string{"room-1"})
+	assert.Nil(t, err)
+	_, err = ding.RemoveEventMeetingRooms(ctx, "union", "event-1", []string{"room-1"})
+	assert.Nil(t, err)
+	_, _, err = ding.ListCalendarEvents(ctx, "union", &RequestListCalendarEvents{
+		TimeMin:    time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		MaxResults: 20,
+		NextToken:  "next",
+	})
+	assert.Nil(t, err)
+
+	events := "/v1.0/calendar/users/union/calendars/primary/events"
+	var calls [][2]string
+	for _, req := range received() {
+		calls = append(calls, [2]string{req.Method, req.Path})
+	}
+	assert.Equal(t, [][2]string{
+		{http.MethodPut, events + "/event-1"},
+		{http.MethodGet, events + "/event-1"},
+		{http.MethodDelete, events + "/event-1"},
+		{http.MethodPost, events + "/event-1/attendees"},
+		{http.MethodPost, events + "/event-1/attendees/batchRemove"},
+		{http.MethodPost, events + "/event-1/meetingRooms"},
+		{http.MethodPost, events + "/event-1/meetingRooms/batchRemove"},
+		{http.MethodGet, events},
+	}, calls)
+
+	reqs := received()
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": "union-2"}}, reqs[4].Body["attendeesToRemove"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"roomId": "room-1"}}, reqs[5].Body["meetingRoomsToAdd"])
+	assert.Equal(t, map[string]string{"timeMin": "2021-06-01T00:00:00Z", "maxResults": "20", "nextToken": "next"}, reqs[7].Query)
+}
+
+func TestClient_CalendarEvent_EmptyID(t *testing.T) {
+	ding, received, closeFn := newGatewayTestClient(func(req *gatewayRequest) (int, string) {
+		return http.StatusOK, `{}`
+	})
+	defer closeFn()
+	ctx := context.Background()
+
+	_, _, err := ding.UpdateCalendarEvent(ctx, "union", &CalendarEvent{Summary: "改期"})
+	assert.True(t, IsInvalidParam(err))
+	_, _, err = ding.GetCalendarEvent(ctx, "union", "")
+	assert.True(t, IsInvalidParam(err))
+	_, err = ding.DeleteCalendarEvent(ctx, "union", "")
+	assert.True(t, IsInvalidParam(err))
+	_, err = ding.RemoveEventMeetingRooms(ctx, "union", "", []string{"room-1"})
+	assert.True(t, IsInvalidParam(err))
+	assert.Len(t, received(), 0)
+}
+
+func TestClient_CalendarEvent_Retry(t *testing.T) {
+	var mu sync.Mutex
+	failures := map[string]int{}
+	ding, received, closeFn := newGatewayTestClient(func(req *gatewayRequest) (int, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := req.Method + " " + req.Path
+		if failures[key]++; failures[key] == 1 {
+			return http.StatusBadGateway, `bad gateway`
+		}
+		return http.StatusOK, `{"id":"event-1"}`
+	})
+	defer closeFn()
+	ctx := context.Background()
+
+	// 创建日程不幂等，5xx时不重试
+	_, _, err := ding.CreateCalendarEvent(ctx, "union", &CalendarEvent{Summary: "周会"})
+	var ge *GatewayErr
+	assert.True(t, errors.As(err, &ge))
+	assert.Equal(t, http.StatusBadGateway, ge.StatusCode)
+	assert.Len(t, received(), 1)
+
+	// 修改、查询日程幂等，5xx时重试
+	_, _, err = ding.UpdateCalendarEvent(ctx, "union", &CalendarEvent{ID: "event-1"})
+	assert.Nil(t, err)
+	_, _, err = ding.GetCalendarEvent(ctx, "union", "event-1")
+	assert.Nil(t, err)
+	assert.Len(t, received(), 5)
+}
+
+func TestClient_CalendarEvent_GatewayError(t *testing.T) {
+	ding, received, closeFn := newGatewayTestClient(func(req *gatewayRequest) (int, string) {
+		switch {
+		case req.Token == "token":
+			return http.StatusUnauthorized, `{"code":"InvalidAuthentication","message":"不合法的access_token","requestid":"r1"}`
+		case req.Method == http.MethodGet:
+			return http.StatusNotFound, `{"code":"eventNotFound","message":"日程不存在","requestid":"r2"}`
+		default:
+			return http.StatusForbidden, `{"code":"forbidden.noPermission","message":"无权限","requestid":"r3"}`
+		}
+	})
+	defer closeFn()
+	ctx := context.Background()
+
+	// access_token过期时刷新后重试
+	_, _, err := ding.GetCalendarEvent(ctx, "union", "event-1")
+	assert.True(t, IsNotFound(err))
+	var ge *GatewayErr
+	assert.True(t, errors.As(err, &ge))
+	assert.Equal(t, "/v1.0/calendar/users/union/calendars/primary/events/event-1", ge.Path)
+	assert.Equal(t, "r2", ge.RequestID)
+	assert.Equal(t, "refreshed", ding.AccessToken())
+	assert.Len(t, received(), 2)
+
+	_, err = ding.AddEventAttendees(ctx, "union", "event-1", []*EventAttendee{{ID: "union-2"}})
+	assert.True(t, IsPermissionDenied(err))
+	assert.Len(t, received(), 3)
+}
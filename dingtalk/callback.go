@@ -0,0 +1,143 @@
+package dingtalk
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// CallbackCrypto 钉钉回调消息的加解密 https://developers.dingtalk.com/document/app/callback-overview
+	CallbackCrypto struct {
+		token     string
+		aesKey    []byte
+		receiveID string // 企业内部应用为AppKey，第三方企业应用为SuiteKey
+	}
+
+	// RequestCallback 回调请求体
+	RequestCallback struct {
+		Encrypt string `json:"encrypt"`
+	}
+
+	// ResponseCallback 回调响应体
+	ResponseCallback struct {
+		MsgSignature string `json:"msg_signature"`
+		TimeStamp    string `json:"timeStamp"`
+		Nonce        string `json:"nonce"`
+		Encrypt      string `json:"encrypt"`
+	}
+)
+
+var (
+	ErrCallbackSignature = errors.New("invalid callback signature")
+	ErrCallbackReceiver  = errors.New("callback receiver mismatched")
+	ErrCallbackPayload   = errors.New("malformed callback payload")
+)
+
+// NewCallbackCrypto token与aesKey为开发者后台配置的签名token及数据加密密钥
+func NewCallbackCrypto(token, aesKey, receiveID string) (*CallbackCrypto, error) {
+	key, err := base64.StdEncoding.DecodeString(aesKey + "=")
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, errors.New("aes key must be 43 characters")
+	}
+	return &CallbackCrypto{token: token, aesKey: key, receiveID: receiveID}, nil
+}
+
+// Signature 计算回调签名
+func (cc *CallbackCrypto) Signature(timestamp, nonce, encrypt string) string {
+	parts := []string{cc.token, timestamp, nonce, encrypt}
+	sort.Strings(parts)
+	sum := sha1.Sum([]byte(strings.Join(parts, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Decrypt 校验签名并解密回调内容
+func (cc *CallbackCrypto) Decrypt(signature, timestamp, nonce, encrypt string) ([]byte, error) {
+	if cc.Signature(timestamp, nonce, encrypt) != signature {
+		return nil, ErrCallbackSignature
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, ErrCallbackPayload
+	}
+	block, err := aes.NewCipher(cc.aesKey)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, cc.aesKey[:aes.BlockSize]).CryptBlocks(plain, data)
+
+	pad := int(plain[len(plain)-1])
+	if pad < 1 || pad > 32 || pad > len(plain) {
+		return nil, ErrCallbackPayload
+	}
+	plain = plain[:len(plain)-pad]
+	// 16字节随机串 + 4字节消息长度 + 消息 + receiveID
+	if len(plain) < 20 {
+		return nil, ErrCallbackPayload
+	}
+	size := int(binary.BigEndian.Uint32(plain[16:20]))
+	if size > len(plain)-20 {
+		return nil, ErrCallbackPayload
+	}
+	if string(plain[20+size:]) != cc.receiveID {
+		return nil, ErrCallbackReceiver
+	}
+	return plain[20 : 20+size], nil
+}
+
+// Encrypt 加密回调的响应内容
+func (cc *CallbackCrypto) Encrypt(msg []byte) (*ResponseCallback, error) {
+	random := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, random); err != nil {
+		return nil, err
+	}
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(msg)))
+
+	buf := bytes.NewBuffer(random)
+	buf.Write(size)
+	buf.Write(msg)
+	buf.WriteString(cc.receiveID)
+	pad := 32 - buf.Len()%32
+	buf.Write(bytes.Repeat([]byte{byte(pad)}, pad))
+
+	block, err := aes.NewCipher(cc.aesKey)
+	if err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	cipher.NewCBCEncrypter(block, cc.aesKey[:aes.BlockSize]).CryptBlocks(data, data)
+
+	encrypt := base64.StdEncoding.EncodeToString(data)
+	timestamp := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
+	nonce := hex.EncodeToString(random[:8])
+	return &ResponseCallback{
+		MsgSignature: cc.Signature(timestamp, nonce, encrypt),
+		TimeStamp:    timestamp,
+		Nonce:        nonce,
+		Encrypt:      encrypt,
+	}, nil
+}
+
+// Success 回调处理成功时需返回的加密响应
+func (cc *CallbackCrypto) Success() (*ResponseCallback, error) {
+	return cc.Encrypt([]byte("success"))
+}
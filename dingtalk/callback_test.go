@@ -0,0 +1,25 @@
+package dingtalk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallbackCrypto(t *testing.T) {
+	cc, err := NewCallbackCrypto("token", "o1w0aum42yaptlz8alnhwikjd3jenzt9cb9wmzptgus", "suite_key")
+	assert.Nil(t, err)
+
+	reply, err := cc.Encrypt([]byte(`{"EventType":"check_url"}`))
+	assert.Nil(t, err)
+	plain, err := cc.Decrypt(reply.MsgSignature, reply.TimeStamp, reply.Nonce, reply.Encrypt)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"EventType":"check_url"}`, string(plain))
+
+	_, err = cc.Decrypt("bad signature", reply.TimeStamp, reply.Nonce, reply.Encrypt)
+	assert.Equal(t, ErrCallbackSignature, err)
+
+	other, _ := NewCallbackCrypto("token", "o1w0aum42yaptlz8alnhwikjd3jenzt9cb9wmzptgus", "other_key")
+	_, err = other.Decrypt(reply.MsgSignature, reply.TimeStamp, reply.Nonce, reply.Encrypt)
+	assert.Equal(t, ErrCallbackReceiver, err)
+}
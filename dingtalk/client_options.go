@@ -0,0 +1,123 @@
+package dingtalk
+
+import (
+	"net/http"
+	"time"
+)
+
+type (
+	// ClientOption 定制Client的HTTP行为
+	ClientOption func(*clientOptions)
+
+	// Middleware 包装http.RoundTripper，可用于代理、mTLS、日志、链路追踪及测试桩，先注册的位于调用链外层
+	Middleware func(next http.RoundTripper) http.RoundTripper
+
+	// RoundTripperFunc 函数形式的http.RoundTripper
+	RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+	clientOptions struct {
+		baseURL     string
+		gatewayURL  string
+		httpClient  *http.Client
+		timeout     time.Duration
+		middlewares []Middleware
+		limiter     *RateLimiter
+		retry       RetryPolicy
+
+		instrumentation Instrumentation
+	}
+)
+
+const (
+	defaultBaseURL    = "https://oapi.dingtalk.com"
+	defaultGatewayURL = "https://api.dingtalk.com"
+	defaultTimeout    = 30 * time.Second
+)
+
+func (fn RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// WithBaseURL 替换服务端API地址，默认为https://oapi.dingtalk.com
+func WithBaseURL(url string) ClientOption {
+	return func(o *clientOptions) {
+		o.baseURL = url
+	}
+}
+
+// WithGatewayURL 替换新版服务端API地址，默认为https://api.dingtalk.com
+func WithGatewayURL(url string) ClientOption {
+	return func(o *clientOptions) {
+		o.gatewayURL = url
+	}
+}
+
+// WithHTTPClient 使用自定义的http.Client，如配置了代理或mTLS的Transport
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithTimeout 设置单次HTTP请求的超时时间，默认30秒
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithMiddleware 追加HTTP中间件
+func WithMiddleware(middlewares ...Middleware) ClientOption {
+	return func(o *clientOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithRateLimiter 使用指定的限流器，多个Client可共享同一个RateLimiter；传入nil关闭限流。默认使用NewDefaultRateLimiter
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(o *clientOptions) {
+		o.limiter = limiter
+	}
+}
+
+// WithRetryPolicy 设置重试策略，默认为DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.retry = policy
+	}
+}
+
+func newClientOptions(opts []ClientOption) *clientOptions {
+	o := &clientOptions{
+		baseURL:    defaultBaseURL,
+		gatewayURL: defaultGatewayURL,
+		limiter:    NewDefaultRateLimiter(),
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// buildHTTPClient 复制用户提供的http.Client，并在其Transport外层依次包装中间件
+func (o *clientOptions) buildHTTPClient() *http.Client {
+	client := &http.Client{Timeout: defaultTimeout}
+	if o.httpClient != nil {
+		c := *o.httpClient
+		client = &c
+	}
+	if o.timeout > 0 {
+		client.Timeout = o.timeout
+	}
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(o.middlewares) - 1; i >= 0; i-- {
+		transport = o.middlewares[i](transport)
+	}
+	client.Transport = transport
+	return client
+}
@@ -0,0 +1,46 @@
+package dingtalk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient_WithMiddleware(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gettoken", r.URL.Path)
+		assert.Equal(t, "outer,inner", r.Header.Get("X-Trace"))
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","access_token":"token","expires_in":7200}`))
+	}))
+	defer srv.Close()
+
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				if v := req.Header.Get("X-Trace"); v != "" {
+					name = v + "," + name
+				}
+				req.Header.Set("X-Trace", name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	ding := NewClient(Option{AppKey: "key", AppSecret: "secret"},
+		WithBaseURL(srv.URL),
+		WithHTTPClient(&http.Client{Transport: http.DefaultTransport}),
+		WithTimeout(5*time.Second),
+		WithMiddleware(tag("outer"), tag("inner")),
+	)
+	ak, _, err := ding.GetAccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "token", ak)
+	assert.Equal(t, []string{"outer", "inner"}, order)
+	assert.Equal(t, 5*time.Second, ding.httpClient.Timeout)
+}
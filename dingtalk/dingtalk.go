@@ -18,31 +18,74 @@ import (
 
 type (
 	Client struct {
-		mu     sync.RWMutex
-		url    string
-		client *requests.Session
-		opt    Option
-		flight singleflight.Group
-		ak     string
+		mu         sync.RWMutex
+		url        string
+		api        string // 新版服务端API网关
+		client     *requests.Session
+		httpClient *http.Client
+		limiter    *RateLimiter
+		retry      RetryPolicy
+		opt        Option
+
+		instrumentation Instrumentation
+		flight          singleflight.Group
+		ak              string
+		suite           *SuiteClient // 第三方企业应用模式下，access_token由suite获取
+
+		ticket          string
+		ticketExpiresAt time.Time
 	}
 )
 
-func NewClient(opt Option) *Client {
+func NewClient(opt Option, opts ...ClientOption) *Client {
+	o := newClientOptions(opts)
 	return &Client{
-		url: "https://oapi.dingtalk.com",
-		client: requests.NewSession(requests.Option{
-			Name:    "github.com/wosai/go-clients/dingtalk",
-			Timeout: 30 * time.Second,
-		}),
-		opt: opt,
+		url:        o.baseURL,
+		api:        o.gatewayURL,
+		client:     requests.NewSession(requests.Option{Name: "github.com/wosai/go-clients/dingtalk"}),
+		httpClient: o.buildHTTPClient(),
+		limiter:    o.limiter,
+		retry:      o.retry,
+		opt:        opt,
+
+		instrumentation: o.instrumentation,
 	}
 }
 
+// derive 创建共享HTTP会话及限流器的新Client
+func (ding *Client) derive(opt Option) *Client {
+	return &Client{
+		url:        ding.url,
+		api:        ding.api,
+		client:     ding.client,
+		httpClient: ding.httpClient,
+		limiter:    ding.limiter,
+		retry:      ding.retry,
+		opt:        opt,
+
+		instrumentation: ding.instrumentation,
+	}
+}
+
+// WithAppOption 替换应用凭证，可在并发调用中安全使用；AppKey或CorpID变化时会丢弃已缓存的access_token及jsapi_ticket
 func (ding *Client) WithAppOption(opt Option) *Client {
+	ding.mu.Lock()
+	defer ding.mu.Unlock()
+	if opt.AppKey != ding.opt.AppKey || opt.CorpID != ding.opt.CorpID {
+		ding.ak = ""
+		ding.ticket = ""
+	}
 	ding.opt = opt
 	return ding
 }
 
+// Option 返回当前使用的应用凭证
+func (ding *Client) Option() Option {
+	ding.mu.RLock()
+	defer ding.mu.RUnlock()
+	return ding.opt
+}
+
 func (ding *Client) AccessToken() string {
 	ding.mu.RLock()
 	defer ding.mu.RUnlock()
@@ -61,19 +104,23 @@ func (ding *Client) GetUserInfoByCode(ctx context.Context, code *RequestGetUserI
 	var res *http.Response
 	ret := new(ResponseGetUserInfoByCode)
 
+	opt := ding.Option()
 	ts := time.Now().UnixNano() / 1e6
-	h := hmac.New(sha256.New, []byte(ding.opt.LoginAppSecret))
+	h := hmac.New(sha256.New, []byte(opt.LoginAppSecret))
 	h.Write([]byte(strconv.FormatInt(ts, 10)))
 	signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
 
-	res, _, err = ding.client.PostWithContext(
-		ctx,
-		ding.url+"/sns/getuserinfo_bycode",
-		requests.Params{
-			Query: requests.Any{"accessKey": ding.opt.LoginAppID, "timestamp": strconv.FormatInt(ts, 10), "signature": signature},
-			Json:  code,
-		},
-		UnmarshalAndParseError(ret))
+	err = ding.observe(ctx, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.url+"/sns/getuserinfo_bycode",
+			requests.Params{
+				Query: requests.Any{"accessKey": opt.LoginAppID, "timestamp": strconv.FormatInt(ts, 10), "signature": signature},
+				Json:  code,
+			},
+			UnmarshalAndParseError(ret))
+		return err
+	})
 
 	if err == nil {
 		return ret.UserInfo, res, nil
@@ -83,21 +130,34 @@ func (ding *Client) GetUserInfoByCode(ctx context.Context, code *RequestGetUserI
 
 // GetAccessToken 获取access_token https://ding-doc.dingtalk.com/document#/org-dev-guide/obtain-access_token
 func (ding *Client) GetAccessToken(ctx context.Context) (string, *http.Response, error) {
-	if ding.opt.IsEmpty() {
+	opt := ding.Option()
+	if ding.suite == nil && opt.IsEmpty() {
 		return "", nil, errors.New("no app provided")
 	}
-	ret := new(ResponseGetAccessToken)
-	res, _, err := ding.client.GetWithContext(
-		ctx,
-		ding.url+"/gettoken",
-		requests.Params{Query: requests.Any{"appkey": ding.opt.AppKey, "appsecret": ding.opt.AppSecret}},
-		UnmarshalAndParseError(ret),
-	)
+
+	var ak string
+	var res *http.Response
+	err := ding.observe(ctx, func(ctx context.Context) error {
+		var err error
+		if ding.suite != nil {
+			ak, res, err = ding.suite.GetCorpToken(ctx, opt.CorpID)
+			return err
+		}
+		ret := new(ResponseGetAccessToken)
+		res, _, err = ding.get(
+			ctx,
+			ding.url+"/gettoken",
+			requests.Params{Query: requests.Any{"appkey": opt.AppKey, "appsecret": opt.AppSecret}},
+			UnmarshalAndParseError(ret),
+		)
+		ak = ret.AccessToken
+		return err
+	})
 	if err != nil {
 		return "", res, err
 	}
-	ding.SetAccessToken(ret.AccessToken)
-	return ret.AccessToken, res, nil
+	ding.SetAccessToken(ak)
+	return ak, res, nil
 }
 
 func (ding *Client) GetUserByUnionID(ctx context.Context, req *RequestGetByUnionID) (*UserGetByUnionId, *http.Response, error) {
@@ -105,8 +165,8 @@ func (ding *Client) GetUserByUnionID(ctx context.Context, req *RequestGetByUnion
 	var res *http.Response
 	var err error
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/user/getbyunionid",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: req},
@@ -120,28 +180,19 @@ func (ding *Client) GetUserByUnionID(ctx context.Context, req *RequestGetByUnion
 	return nil, res, err
 }
 
+// RetryOnAccessTokenExpired access_token过期时刷新并重试fn，最多retry次
 func (ding *Client) RetryOnAccessTokenExpired(ctx context.Context, retry int, fn func() error) (err error) {
 	for i := 0; i < retry+1; i++ {
 		err = fn()
 		if err == nil {
 			return nil
 		}
-
-		var de *DingtalkErr
-		if errors.As(err, &de) && err.(*DingtalkErr).IsAccessTokenExpired() {
-			_, akErr, _ := ding.flight.Do("access_token", func() (interface{}, error) {
-				ak, _, reqErr := ding.GetAccessToken(ctx)
-				ding.flight.Forget("access_token")
-				return ak, reqErr
-			})
-			if akErr != nil {
-				return fmt.Errorf("%w | %s", err, akErr.Error())
-			} else {
-				continue
-			}
-		} else {
+		if !isAccessTokenExpired(err) {
 			return err
 		}
+		if akErr := ding.refreshAccessToken(ctx); akErr != nil {
+			return fmt.Errorf("%w | %s", err, akErr.Error())
+		}
 	}
 	return err
 }
@@ -152,8 +203,8 @@ func (ding *Client) GetOrganizationUserCount(ctx context.Context, onlyActive int
 	var err error
 	ret := new(ResponseOrganizationUserCount)
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.GetWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.get(
 			ctx,
 			ding.url+"/user/get_org_user_count",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken(), "onlyActive": strconv.Itoa(onlyActive)}},
@@ -173,8 +224,8 @@ func (ding *Client) GetUserInfoV2(ctx context.Context, req *RequestUserGet) (*Re
 	var err error
 	ret := new(ResponseGetUserInfo)
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/v2/user/get",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: req},
@@ -191,8 +242,8 @@ func (ding *Client) GetUserInfoByMobileV2(ctx context.Context, mobile string) (s
 	var res *http.Response
 	var err error
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/v2/user/getbymobile",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: requests.Any{"mobile": mobile}},
@@ -212,8 +263,8 @@ func (ding *Client) ListParentDeptByUserV2(ctx context.Context, userid string) (
 	var res *http.Response
 	var err error
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/v2/department/listparentbyuser",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: requests.Any{"userid": userid}},
@@ -230,12 +281,12 @@ func (ding *Client) GetDepartment(ctx context.Context, req *RequestDepartmentInf
 	var res *http.Response
 	var err error
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
 		query := requests.Any{"access_token": ding.AccessToken(), "id": req.DeptId}
 		if req.Language != "" {
 			query["lang"] = string(req.Language)
 		}
-		res, _, err = ding.client.GetWithContext(
+		res, _, err = ding.get(
 			ctx,
 			ding.url+"/department/get",
 			requests.Params{Query: query},
@@ -252,8 +303,8 @@ func (ding *Client) CreateProcessInstance(ctx context.Context, req *RequestCreat
 	var err error
 	var res *http.Response
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, nonIdempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/processinstance/create",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: req},
@@ -270,8 +321,8 @@ func (ding *Client) GetProcessInstance(ctx context.Context, processInstanceID st
 	var err error
 	var res *http.Response
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/processinstance/get",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: requests.Any{"process_instance_id": processInstanceID}},
@@ -288,8 +339,8 @@ func (ding *Client) GetDepartmentV2(ctx context.Context, deptID int) (*Departmen
 	var res *http.Response
 	var err error
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/v2/department/get",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: &RequestDepartmentInfoV2{DeptID: deptID}},
@@ -306,8 +357,8 @@ func (ding *Client) GetParentDepartmentV2(ctx context.Context, deptID int) (*Par
 	var res *http.Response
 	var err error
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/v2/department/listparentbydept",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: &RequestDepartmentInfoV2{DeptID: deptID}},
@@ -324,8 +375,8 @@ func (ding *Client) GetSubDepartmentV2(ctx context.Context, deptID int) (*SubDep
 	var res *http.Response
 	var err error
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/v2/department/listsubid",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: &RequestDepartmentInfoV2{DeptID: deptID}},
@@ -342,8 +393,8 @@ func (ding *Client) GetDepartmentUserList(ctx context.Context, deptID, cursor, s
 	var res *http.Response
 	var err error
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/v2/user/list",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: &RequestDepartmentUserList{DeptID: deptID, Cursor: cursor, Size: size}},
@@ -359,8 +410,8 @@ func (ding *Client) GetDepartmentListParentByUser(ctx context.Context, dingID st
 	var res *http.Response
 	var err error
 
-	err = ding.RetryOnAccessTokenExpired(ctx, 1, func() error {
-		res, _, err = ding.client.PostWithContext(
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
 			ctx,
 			ding.url+"/topapi/v2/department/listparentbyuser",
 			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: &RequestDepartmentListParentByUser{UserID: dingID}},
@@ -2,48 +2,63 @@ package dingtalk
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"github.com/stretchr/testify/assert"
+	"errors"
 	"os"
-	"strconv"
 	"testing"
-)
 
-var DingClient *Client
-var ctx = context.Background()
-var testUser *ResponseGetUserInfo
-var DeptID int
+	"github.com/stretchr/testify/assert"
+	"github.com/wosai/go-clients/dingtalk/dingtalktest"
+)
 
 var (
-	AgentID   = os.Getenv("AgentID")
-	AppKey    = os.Getenv("AppKey")
-	AppSecret = os.Getenv("AppSecret")
-	UserID    = os.Getenv("UserID")
-	_deptID    = os.Getenv("DeptID")
+	ctx        = context.Background()
+	fake       *dingtalktest.Server
+	DingClient *Client
+	testUser   *ResponseGetUserInfo
+)
+
+const (
+	AgentID = dingtalktest.AgentID
+	UserID  = "manager"
+	DeptID  = 2
 )
 
-func init() {
+func TestMain(m *testing.M) {
+	fake = dingtalktest.NewServer()
+	fake.AddDepartments(
+		dingtalktest.Department{ID: DeptID, Name: "研发部", ManagerUserIDs: []string{UserID}},
+		dingtalktest.Department{ID: 3, Name: "平台组", ParentID: DeptID},
+	)
+	fake.AddUsers(
+		dingtalktest.User{UserID: UserID, Name: "张三", Mobile: "13800000000", DeptIDs: []int{DeptID}, Active: true},
+		dingtalktest.User{UserID: "developer", Name: "李四", Mobile: "13800000001", DeptIDs: []int{3}, Active: true},
+		dingtalktest.User{UserID: "intern", Name: "王五", DeptIDs: []int{3}},
+	)
+
+	DingClient = newTestClient()
 	var err error
-	DeptID, err = strconv.Atoi(_deptID)
-	if err != nil {
-		panic(fmt.Errorf("%w DeptID: %s not int", err, _deptID))
-	}
-	DingClient = NewClient(Option{AgentID: AgentID, AppKey: AppKey, AppSecret: AppSecret})
-	_, _, err = DingClient.GetAccessToken(ctx)
-	if err != nil {
-		panic("get access token fail:" + err.Error())
-	}
 	testUser, _, err = DingClient.GetUserInfoV2(ctx, &RequestUserGet{UserID: UserID})
 	if err != nil {
-		panic("userid not exit" + err.Error())
+		panic("get test user fail: " + err.Error())
 	}
+
+	code := m.Run()
+	fake.Close()
+	os.Exit(code)
+}
+
+func newTestClient() *Client {
+	return NewClient(
+		Option{AgentID: AgentID, AppKey: dingtalktest.AppKey, AppSecret: dingtalktest.AppSecret},
+		WithBaseURL(fake.URL),
+		WithRetryPolicy(fastRetry),
+	)
 }
 
 func TestClient_WithAppOption(t *testing.T) {
 	opt := Option{AgentID: "AgentID", AppKey: "AppKey", AppSecret: "AppSecret"}
-	DingClient.WithAppOption(opt)
-	assert.Equal(t, DingClient.opt, opt)
+	client := newTestClient().WithAppOption(opt)
+	assert.Equal(t, client.opt, opt)
 }
 
 func TestClient_GetUserInfoByCode(t *testing.T) {
@@ -51,21 +66,34 @@ func TestClient_GetUserInfoByCode(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestClient_GetAccessToken(t *testing.T) {
+	_, _, err := NewClient(Option{AppKey: "AppKey", AppSecret: "AppSecret"}, WithBaseURL(fake.URL)).GetAccessToken(ctx)
+	var de *DingtalkErr
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, 40089, de.ErrorCode)
+}
+
 func TestClient_GetUserByUnionID(t *testing.T) {
 	user, _, err := DingClient.GetUserByUnionID(ctx, &RequestGetByUnionID{UnionID: testUser.Result.UnionID})
 	assert.Nil(t, err)
 	assert.Equal(t, user.UserID, testUser.Result.UserID)
+
+	_, _, err = DingClient.GetUserByUnionID(ctx, &RequestGetByUnionID{UnionID: "nobody"})
+	assert.True(t, IsUserNotExist(err))
 }
 
 func TestClient_GetDepartment(t *testing.T) {
-	deptInfo, _, err := DingClient.GetDepartment(ctx, &RequestDepartmentInfo{DeptId: "155387235"})
+	deptInfo, _, err := DingClient.GetDepartment(ctx, &RequestDepartmentInfo{DeptId: "2"})
 	assert.Nil(t, err)
-	assert.NotNil(t, deptInfo)
-	deptInfo, _, err = DingClient.GetDepartment(ctx, &RequestDepartmentInfo{DeptId: "155387235", Language: EN_US})
+	assert.Equal(t, "研发部", deptInfo.Name)
+	assert.Equal(t, UserID, deptInfo.DeptManagerUseridList)
+	deptInfo, _, err = DingClient.GetDepartment(ctx, &RequestDepartmentInfo{DeptId: "2", Language: EN_US})
 	assert.Nil(t, err)
 	assert.NotNil(t, deptInfo)
 	_, _, err = DingClient.GetDepartment(ctx, &RequestDepartmentInfo{DeptId: ""})
-	assert.NotNil(t, err)
+	assert.True(t, IsInvalidParam(err))
+	_, _, err = DingClient.GetDepartment(ctx, &RequestDepartmentInfo{DeptId: "404"})
+	assert.True(t, IsNotFound(err))
 }
 
 func TestClient_GetUserInfoByMobileV2(t *testing.T) {
@@ -78,16 +106,22 @@ func TestClient_GetUserInfoV2(t *testing.T) {
 	user, _, err := DingClient.GetUserInfoV2(ctx, &RequestUserGet{UserID: testUser.Result.UserID})
 	assert.Nil(t, err)
 	assert.Equal(t, user.Result.UserID, testUser.Result.UserID)
+	assert.Equal(t, []DeptLeader{{DeptId: DeptID, Leader: true}}, user.Result.LeaderInDept)
 }
 
 func TestClient_ListParentDeptByUserV2(t *testing.T) {
-	_, _, err := DingClient.ListParentDeptByUserV2(ctx, testUser.Result.UserID)
+	parents, _, err := DingClient.ListParentDeptByUserV2(ctx, "developer")
 	assert.Nil(t, err)
+	assert.Equal(t, []ParentDeptIds{{ParentDeptIdList: []int{3, DeptID, 1}}}, parents.ParentDeptList)
 }
 
 func TestClient_GetOrganizationUserCount(t *testing.T) {
-	_, _, err := DingClient.GetOrganizationUserCount(ctx, 1)
+	count, _, err := DingClient.GetOrganizationUserCount(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	count, _, err = DingClient.GetOrganizationUserCount(ctx, 0)
 	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
 }
 
 func TestClient_CreateProcessInstance(t *testing.T) {
@@ -103,9 +137,9 @@ func TestClient_CreateProcessInstance(t *testing.T) {
 			},
 		},
 		AgentID:          AgentID,
-		DeptID:           "477274342",
+		DeptID:           "2",
 		ProcessCode:      "PROC-0F200284-842E-46FF-9ACC-64DB3176150C",
-		OriginatorUserID: UserID,
+		OriginatorUserID: "developer",
 		ApproversV2: []ProcessApprovers{
 			{
 				TaskActionType: "OR",
@@ -117,51 +151,127 @@ func TestClient_CreateProcessInstance(t *testing.T) {
 			},
 		},
 	}
-	id, _, _ := DingClient.CreateProcessInstance(ctx, req)
-	fmt.Println(id)
+	id, _, err := DingClient.CreateProcessInstance(ctx, req)
+	assert.Nil(t, err)
+	pi, ok := fake.ProcessInstance(id)
+	assert.True(t, ok)
+	assert.Equal(t, req.ProcessCode, pi.ProcessCode)
+	assert.Len(t, pi.FormValues, 2)
 }
 
 func TestClient_GetProcessInstance(t *testing.T) {
-	//"f4b924d1-b13b-4dc1-ae5a-aea4716ecb5c"
-	//"01b3a55b-d92d-40c7-ae70-87b13daf11e5"
-	pi, _, _ := DingClient.GetProcessInstance(ctx, "01b3a55b-d92d-40c7-ae70-87b13daf11e5")
-	b, _ := json.Marshal(pi)
-	fmt.Println(string(b))
+	id, _, err := DingClient.CreateProcessInstance(ctx, &RequestCreateProcessInstance{
+		ProcessCode:         "PROC-LEAVE",
+		OriginatorUserID:    "developer",
+		DeptID:              "3",
+		FormComponentValues: []*FormComponentValue{{Name: "请假类型", Value: "年假"}},
+		ApproversV2:         []ProcessApprovers{{TaskActionType: "NONE", UserIDs: []string{UserID}}},
+	})
+	assert.Nil(t, err)
+
+	pi, _, err := DingClient.GetProcessInstance(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "RUNNING", pi.Status)
+	assert.Equal(t, "developer", pi.OriginatorUserID)
+	assert.Equal(t, []string{UserID}, pi.ApproverUserIDs)
+	assert.Equal(t, "年假", pi.FormComponentValues[0].Value)
+	assert.False(t, pi.CreateTime.IsZero())
+
+	_, _, err = DingClient.GetProcessInstance(ctx, "01b3a55b-d92d-40c7-ae70-87b13daf11e5")
+	assert.NotNil(t, err)
 }
 
 func TestClient_1(t *testing.T) {
 	// 获取用户父部门列表
-	res, _, _ := DingClient.ListParentDeptByUserV2(ctx, UserID)
-	b, _ := json.Marshal(res)
-	fmt.Println(string(b))
+	res, _, err := DingClient.ListParentDeptByUserV2(ctx, UserID)
+	assert.Nil(t, err)
 	for _, dept := range res.ParentDeptList[0].ParentDeptIdList {
 		// 获取部门详情
-		resp, _, _ := DingClient.GetDepartmentV2(ctx, dept)
-		b, _ = json.Marshal(resp)
-		fmt.Println(string(b))
+		resp, _, err := DingClient.GetDepartmentV2(ctx, dept)
+		assert.Nil(t, err)
+		assert.Equal(t, dept, resp.DeptID)
 	}
 }
 
 func TestClient_GetParentDepartmentV2(t *testing.T) {
-	info, _, err := DingClient.GetParentDepartmentV2(ctx, DeptID)
+	info, _, err := DingClient.GetParentDepartmentV2(ctx, 3)
 	assert.Nil(t, err)
-	fmt.Println(info.ParentIDList)
+	assert.Equal(t, []int{3, DeptID, 1}, info.ParentIDList)
 }
 
 func TestClient_GetSubDepartmentV2(t *testing.T) {
 	info, _, err := DingClient.GetSubDepartmentV2(ctx, DeptID)
 	assert.Nil(t, err)
-	fmt.Println(info.SubIDList)
+	assert.Equal(t, []int{3}, info.SubIDList)
 }
 
 func TestClient_GetDepartmentUserList(t *testing.T) {
-	info, _, err := DingClient.GetDepartmentUserList(ctx, DeptID, 0, 100)
+	info, _, err := DingClient.GetDepartmentUserList(ctx, 3, 0, 1)
 	assert.Nil(t, err)
-	fmt.Println(info)
+	assert.True(t, info.HasMore)
+	assert.Equal(t, "developer", info.List[0].UserID)
+	info, _, err = DingClient.GetDepartmentUserList(ctx, 3, info.NextCursor, 1)
+	assert.Nil(t, err)
+	assert.False(t, info.HasMore)
+	assert.Equal(t, "intern", info.List[0].UserID)
 }
 
 func TestClient_GetDepartmentListParentByUser(t *testing.T) {
 	info, _, err := DingClient.GetDepartmentListParentByUser(ctx, UserID)
 	assert.Nil(t, err)
-	fmt.Println(info)
+	assert.Equal(t, []int{DeptID, 1}, info.ParentList[0].ParentDeptIDList)
+}
+
+func TestClient_GetJSAPITicket(t *testing.T) {
+	ticket, _, err := DingClient.GetJSAPITicket(ctx)
+	assert.Nil(t, err)
+	cached, res, err := DingClient.GetJSAPITicket(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, ticket, cached)
+}
+
+func TestClient_SignJSAPIConfig(t *testing.T) {
+	conf, _, err := DingClient.SignJSAPIConfig(ctx, "http://www.dingtalk.com/?a=b", "nonce", 1414587457)
+	assert.Nil(t, err)
+	assert.Equal(t, AgentID, conf.AgentID)
+	assert.NotEmpty(t, conf.Signature)
+}
+
+func TestClient_TokenExpired(t *testing.T) {
+	client := newTestClient()
+	_, _, err := client.GetUserInfoV2(ctx, &RequestUserGet{UserID: UserID})
+	assert.Nil(t, err)
+	tokens := fake.Requests("/gettoken")
+
+	fake.ExpireTokens()
+	user, _, err := client.GetUserInfoV2(ctx, &RequestUserGet{UserID: UserID})
+	assert.Nil(t, err)
+	assert.Equal(t, UserID, user.Result.UserID)
+	assert.Equal(t, tokens+1, fake.Requests("/gettoken"))
+}
+
+func TestClient_InjectedError(t *testing.T) {
+	defer fake.ClearErrors()
+	client := newTestClient()
+
+	fake.InjectError("/topapi/v2/department/listsubid", dingtalktest.Fault{ErrCode: 60011, ErrMsg: "没有调用该接口的权限", Times: 1})
+	_, _, err := client.GetSubDepartmentV2(ctx, DeptID)
+	assert.True(t, IsPermissionDenied(err))
+	_, _, err = client.GetSubDepartmentV2(ctx, DeptID)
+	assert.Nil(t, err)
+
+	// 限流及网关错误会重试
+	before := fake.Requests("/topapi/v2/department/get")
+	rateLimited := dingtalktest.FaultRateLimited
+	rateLimited.Times = 2
+	fake.InjectError("/topapi/v2/department/get", rateLimited)
+	_, _, err = client.GetDepartmentV2(ctx, DeptID)
+	assert.Nil(t, err)
+	assert.Equal(t, before+3, fake.Requests("/topapi/v2/department/get"))
+
+	fake.InjectError("/topapi/v2/department/get", dingtalktest.FaultServerError)
+	_, _, err = client.GetDepartmentV2(ctx, DeptID)
+	var he *HTTPError
+	assert.True(t, errors.As(err, &he))
 }
@@ -0,0 +1,130 @@
+// Package dingtalkotel 基于OpenTelemetry实现dingtalk.Instrumentation，为每次钉钉接口调用生成span并记录指标
+package dingtalkotel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wosai/go-clients/dingtalk"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/wosai/go-clients/dingtalk/dingtalkotel"
+
+const (
+	AttributePath           = attribute.Key("dingtalk.path")
+	AttributeMethod         = attribute.Key("http.method")
+	AttributeStatusCode     = attribute.Key("http.status_code")
+	AttributeErrorCode      = attribute.Key("dingtalk.errcode")
+	AttributeAttempts       = attribute.Key("dingtalk.attempts")
+	AttributeRetries        = attribute.Key("dingtalk.retries")
+	AttributeTokenRefreshes = attribute.Key("dingtalk.token_refreshes")
+)
+
+type (
+	// Option Instrumentation的配置项
+	Option func(*Instrumentation)
+
+	// Instrumentation 钉钉接口调用的OpenTelemetry观测实现
+	Instrumentation struct {
+		tracer         trace.Tracer
+		meter          metric.Meter
+		tracerProvider trace.TracerProvider
+		meterProvider  metric.MeterProvider
+
+		calls          metric.Int64Counter
+		duration       metric.Float64Histogram
+		retries        metric.Int64Counter
+		tokenRefreshes metric.Int64Counter
+	}
+)
+
+var _ dingtalk.Instrumentation = (*Instrumentation)(nil)
+
+// WithTracerProvider 指定TracerProvider，默认为otel.GetTracerProvider()
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(i *Instrumentation) {
+		i.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider 指定MeterProvider，默认为otel.GetMeterProvider()
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(i *Instrumentation) {
+		i.meterProvider = provider
+	}
+}
+
+// New 创建Instrumentation，通过dingtalk.WithInstrumentation注入dingtalk.Client
+func New(opts ...Option) (*Instrumentation, error) {
+	i := &Instrumentation{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	i.tracer = i.tracerProvider.Tracer(instrumentationName)
+	i.meter = i.meterProvider.Meter(instrumentationName)
+
+	var err, e error
+	i.calls, e = i.meter.Int64Counter("dingtalk.client.calls", metric.WithDescription("Number of DingTalk API calls"))
+	err = errors.Join(err, e)
+	i.duration, e = i.meter.Float64Histogram("dingtalk.client.duration", metric.WithDescription("Duration of DingTalk API calls, including retries"), metric.WithUnit("s"))
+	err = errors.Join(err, e)
+	i.retries, e = i.meter.Int64Counter("dingtalk.client.retries", metric.WithDescription("Number of retried DingTalk API requests"))
+	err = errors.Join(err, e)
+	i.tokenRefreshes, e = i.meter.Int64Counter("dingtalk.client.token_refreshes", metric.WithDescription("Number of access token refreshes caused by expired tokens"))
+	err = errors.Join(err, e)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// StartCall 实现dingtalk.Instrumentation，接口路径在调用结束时才能确定，因此span名称在EndCall中设置
+func (i *Instrumentation) StartCall(ctx context.Context) context.Context {
+	ctx, _ = i.tracer.Start(ctx, "dingtalk", trace.WithSpanKind(trace.SpanKindClient))
+	return ctx
+}
+
+// EndCall 实现dingtalk.Instrumentation
+func (i *Instrumentation) EndCall(ctx context.Context, info *dingtalk.CallInfo) {
+	retries := info.Attempts - 1
+	if retries < 0 {
+		retries = 0
+	}
+	attrs := []attribute.KeyValue{AttributePath.String(info.Path), AttributeMethod.String(info.Method)}
+	if info.ErrorCode != "" {
+		attrs = append(attrs, AttributeErrorCode.String(info.ErrorCode))
+	}
+
+	span := trace.SpanFromContext(ctx)
+	span.SetName("dingtalk " + info.Path)
+	span.SetAttributes(attrs...)
+	span.SetAttributes(
+		AttributeStatusCode.Int(info.StatusCode),
+		AttributeAttempts.Int(info.Attempts),
+		AttributeRetries.Int(retries),
+		AttributeTokenRefreshes.Int(info.TokenRefreshes),
+	)
+	if info.Err != nil {
+		span.RecordError(info.Err)
+		span.SetStatus(codes.Error, info.Err.Error())
+	}
+	span.End(trace.WithTimestamp(info.Start.Add(info.Duration)))
+
+	set := metric.WithAttributes(attrs...)
+	i.calls.Add(ctx, 1, set)
+	i.duration.Record(ctx, info.Duration.Seconds(), set)
+	if retries > 0 {
+		i.retries.Add(ctx, int64(retries), set)
+	}
+	if info.TokenRefreshes > 0 {
+		i.tokenRefreshes.Add(ctx, int64(info.TokenRefreshes), set)
+	}
+}
@@ -0,0 +1,53 @@
+package dingtalkotel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wosai/go-clients/dingtalk"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInstrumentation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errcode":60121,"errmsg":"找不到该用户"}`))
+	}))
+	defer srv.Close()
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	inst, err := New(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	assert.Nil(t, err)
+
+	ding := dingtalk.NewClient(dingtalk.Option{AppKey: "key", AppSecret: "secret"}, dingtalk.WithBaseURL(srv.URL), dingtalk.WithInstrumentation(inst))
+	ding.SetAccessToken("token")
+	_, _, err = ding.GetUserByUnionID(context.Background(), &dingtalk.RequestGetByUnionID{UnionID: "union"})
+	assert.True(t, dingtalk.IsUserNotExist(err))
+
+	ended := spans.Ended()
+	if assert.Len(t, ended, 1) {
+		assert.Equal(t, "dingtalk /topapi/user/getbyunionid", ended[0].Name())
+		assert.Contains(t, ended[0].Attributes(), AttributeErrorCode.String("60121"))
+		assert.Contains(t, ended[0].Attributes(), AttributeRetries.Int(0))
+	}
+
+	var rm metricdata.ResourceMetrics
+	assert.Nil(t, reader.Collect(context.Background(), &rm))
+	names := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			names[m.Name] = true
+		}
+	}
+	assert.True(t, names["dingtalk.client.calls"])
+	assert.True(t, names["dingtalk.client.duration"])
+}
@@ -0,0 +1,86 @@
+package dingtalktest
+
+import "time"
+
+type (
+	// User 通讯录用户
+	User struct {
+		UserID    string
+		UnionID   string
+		Name      string
+		Mobile    string
+		Title     string
+		Email     string
+		JobNumber string
+		DeptIDs   []int
+		Active    bool
+		Admin     bool
+		Boss      bool
+	}
+
+	// Department 部门，ID为1的根部门在NewServer时自动创建
+	Department struct {
+		ID             int
+		Name           string
+		ParentID       int
+		ManagerUserIDs []string
+	}
+
+	// FormValue 审批表单控件值
+	FormValue struct {
+		Name  string `json:"name,omitempty"`
+		Value string `json:"value,omitempty"`
+	}
+
+	// ProcessInstance 审批实例，通过/topapi/processinstance/create创建的实例状态为RUNNING
+	ProcessInstance struct {
+		ID               string
+		ProcessCode      string
+		Title            string
+		OriginatorUserID string
+		OriginatorDeptID string
+		Status           string // RUNNING、COMPLETED、TERMINATED
+		Result           string // agree、refuse
+		ApproverUserIDs  []string
+		CCUserIDs        []string
+		FormValues       []FormValue
+		CreateTime       time.Time
+		FinishTime       time.Time
+	}
+
+	// Employee 智能人事花名册中的员工，Dimission不为空时为离职员工
+	Employee struct {
+		UserID    string
+		Status    int               // 员工状态：1待入职 2试用期 3正式 5待离职 -1无状态
+		Fields    map[string]string // 花名册字段code -> 取值，如"sys00-name"
+		Labels    map[string]string // 选项类字段code -> 选项文本
+		Dimission *Dimission
+	}
+
+	// Dimission 员工离职信息
+	Dimission struct {
+		LastWorkDay time.Time
+		ReasonType  int
+		ReasonMemo  string
+		PreStatus   int // 离职前工作状态：1待入职 2试用期 3正式
+		Status      int // 1待离职 2已离职
+	}
+
+	// Fault 注入的错误，请求命中后按Fault返回而不再处理
+	Fault struct {
+		StatusCode int    // HTTP状态码，默认200
+		ErrCode    int    // errcode
+		ErrMsg     string // errmsg
+		SubCode    string // sub_code，ErrCode为88时有效
+		SubMsg     string
+		Times      int // 生效次数，小于等于0时一直生效
+	}
+)
+
+// 常用的注入错误
+var (
+	FaultSystemBusy   = Fault{ErrCode: -1, ErrMsg: "系统繁忙"}
+	FaultRateLimited  = Fault{ErrCode: 88, ErrMsg: "ding talk error[subcode=90018]", SubCode: "90018", SubMsg: "当前企业正在调用此接口的QPS已达上限"}
+	FaultServerError  = Fault{StatusCode: 502, ErrMsg: "bad gateway"}
+	FaultTokenExpired = Fault{ErrCode: 40014, ErrMsg: "不合法的access_token"}
+)
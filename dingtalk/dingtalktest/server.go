@@ -0,0 +1,767 @@
+// Package dingtalktest 提供基于httptest的钉钉服务端API模拟，覆盖gettoken、通讯录用户、部门、审批及智能人事接口，
+// 用于在没有真实应用凭证的环境下测试dingtalk.Client及其调用方：
+//
+//	srv := dingtalktest.NewServer()
+//	defer srv.Close()
+//	srv.AddUsers(dingtalktest.User{UserID: "manager", Name: "张三", DeptIDs: []int{1}})
+//	client := dingtalk.NewClient(
+//		dingtalk.Option{AgentID: dingtalktest.AgentID, AppKey: dingtalktest.AppKey, AppSecret: dingtalktest.AppSecret},
+//		dingtalk.WithBaseURL(srv.URL),
+//	)
+//
+// 为避免与dingtalk包的测试形成循环引用，本包不依赖dingtalk包，响应均按钉钉文档的JSON格式构造。
+package dingtalktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AppKey 默认的应用AppKey
+	AppKey = "dingtalktest"
+	// AppSecret 默认的应用AppSecret
+	AppSecret = "dingtalktest-secret"
+	// AgentID 默认的应用AgentID
+	AgentID = "10000"
+	// RootDeptID 根部门ID
+	RootDeptID = 1
+	// TokenTTL access_token默认有效期
+	TokenTTL = 2 * time.Hour
+	// MaxHRMEmployees 花名册接口单次请求的userid上限
+	MaxHRMEmployees = 100
+	// MaxHRMDimission 离职信息接口单次请求的userid上限
+	MaxHRMDimission = 50
+)
+
+type (
+	// Server 模拟的钉钉服务端，所有方法并发安全
+	Server struct {
+		*httptest.Server
+
+		mu        sync.Mutex
+		appKey    string
+		appSecret string
+		tokenTTL  time.Duration
+		seq       int
+		tokens    map[string]time.Time
+		users     map[string]*User
+		depts     map[int]*Department
+		instances map[string]*ProcessInstance
+		employees map[string]*Employee
+		faults    map[string]*Fault
+		requests  map[string]int
+	}
+
+	handler func(r *http.Request) (interface{}, *Fault)
+)
+
+// NewServer 启动模拟服务端，使用完毕后需调用Close
+func NewServer() *Server {
+	s := &Server{
+		appKey:    AppKey,
+		appSecret: AppSecret,
+		tokenTTL:  TokenTTL,
+		tokens:    map[string]time.Time{},
+		users:     map[string]*User{},
+		depts:     map[int]*Department{RootDeptID: {ID: RootDeptID, Name: "dingtalktest"}},
+		instances: map[string]*ProcessInstance{},
+		employees: map[string]*Employee{},
+		faults:    map[string]*Fault{},
+		requests:  map[string]int{},
+	}
+	s.Server = httptest.NewServer(s.routes())
+	return s
+}
+
+// SetCredentials 修改gettoken校验的AppKey及AppSecret
+func (s *Server) SetCredentials(appKey, appSecret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appKey, s.appSecret = appKey, appSecret
+}
+
+// SetTokenTTL 修改此后签发的access_token有效期
+func (s *Server) SetTokenTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenTTL = ttl
+}
+
+// ExpireTokens 使已签发的access_token全部失效，后续请求返回40014直到重新获取
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = map[string]time.Time{}
+}
+
+// InjectError 为指定接口注入错误，path如"/topapi/v2/user/get"
+func (s *Server) InjectError(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = &fault
+}
+
+// ClearErrors 清除所有注入的错误
+func (s *Server) ClearErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = map[string]*Fault{}
+}
+
+// Requests 返回指定接口收到的请求数（含失败请求）
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// AddUsers 添加或覆盖用户
+func (s *Server) AddUsers(users ...User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range users {
+		u := users[i]
+		if u.UnionID == "" {
+			u.UnionID = "union-" + u.UserID
+		}
+		if len(u.DeptIDs) == 0 {
+			u.DeptIDs = []int{RootDeptID}
+		}
+		s.users[u.UserID] = &u
+	}
+}
+
+// AddDepartments 添加或覆盖部门，ParentID为0时挂在根部门下
+func (s *Server) AddDepartments(depts ...Department) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range depts {
+		d := depts[i]
+		if d.ParentID == 0 && d.ID != RootDeptID {
+			d.ParentID = RootDeptID
+		}
+		s.depts[d.ID] = &d
+	}
+}
+
+// AddProcessInstances 添加或覆盖审批实例
+func (s *Server) AddProcessInstances(instances ...ProcessInstance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range instances {
+		pi := instances[i]
+		s.instances[pi.ID] = &pi
+	}
+}
+
+// AddEmployees 添加或覆盖智能人事员工
+func (s *Server) AddEmployees(employees ...Employee) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range employees {
+		e := employees[i]
+		s.employees[e.UserID] = &e
+	}
+}
+
+// ProcessInstance 返回审批实例，用于断言创建的实例
+func (s *Server) ProcessInstance(id string) (ProcessInstance, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pi, ok := s.instances[id]
+	if !ok {
+		return ProcessInstance{}, false
+	}
+	return *pi, true
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gettoken", s.handle(false, s.getToken))
+	mux.HandleFunc("/get_jsapi_ticket", s.handle(true, s.getJSAPITicket))
+	mux.HandleFunc("/user/get_org_user_count", s.handle(true, s.getOrgUserCount))
+	mux.HandleFunc("/topapi/user/getbyunionid", s.handle(true, s.getUserByUnionID))
+	mux.HandleFunc("/topapi/v2/user/get", s.handle(true, s.getUser))
+	mux.HandleFunc("/topapi/v2/user/getbymobile", s.handle(true, s.getUserByMobile))
+	mux.HandleFunc("/topapi/v2/user/list", s.handle(true, s.listDeptUsers))
+	mux.HandleFunc("/department/get", s.handle(true, s.getDepartment))
+	mux.HandleFunc("/topapi/v2/department/get", s.handle(true, s.getDepartmentV2))
+	mux.HandleFunc("/topapi/v2/department/listparentbyuser", s.handle(true, s.listParentByUser))
+	mux.HandleFunc("/topapi/v2/department/listparentbydept", s.handle(true, s.listParentByDept))
+	mux.HandleFunc("/topapi/v2/department/listsubid", s.handle(true, s.listSubDeptID))
+	mux.HandleFunc("/topapi/processinstance/create", s.handle(true, s.createProcessInstance))
+	mux.HandleFunc("/topapi/processinstance/get", s.handle(true, s.getProcessInstance))
+	mux.HandleFunc("/topapi/smartwork/hrm/employee/v2/list", s.handle(true, s.listEmployees))
+	mux.HandleFunc("/topapi/smartwork/hrm/employee/queryonjob", s.handle(true, s.queryEmployees(func(e *Employee, status []string) bool {
+		return e.Dimission == nil && e.Status != 1 && containsString(status, strconv.Itoa(e.Status))
+	})))
+	mux.HandleFunc("/topapi/smartwork/hrm/employee/querypreentry", s.handle(true, s.queryEmployees(func(e *Employee, _ []string) bool {
+		return e.Dimission == nil && e.Status == 1
+	})))
+	mux.HandleFunc("/topapi/smartwork/hrm/employee/querydimission", s.handle(true, s.queryEmployees(func(e *Employee, _ []string) bool {
+		return e.Dimission != nil
+	})))
+	mux.HandleFunc("/topapi/smartwork/hrm/employee/listdimission", s.handle(true, s.listDimission))
+	return mux
+}
+
+// handle 依次处理错误注入、access_token校验，handler在持有锁的情况下执行
+func (s *Server) handle(auth bool, h handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		ret, fault := s.serve(r, auth, h)
+		s.mu.Unlock()
+
+		if fault != nil {
+			writeFault(w, fault)
+			return
+		}
+		writeJSON(w, http.StatusOK, ret)
+	}
+}
+
+func (s *Server) serve(r *http.Request, auth bool, h handler) (interface{}, *Fault) {
+	s.requests[r.URL.Path]++
+	if f, ok := s.faults[r.URL.Path]; ok {
+		fault := *f
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				delete(s.faults, r.URL.Path)
+			}
+		}
+		return nil, &fault
+	}
+	if auth {
+		expiresAt, ok := s.tokens[r.URL.Query().Get("access_token")]
+		if !ok || time.Now().After(expiresAt) {
+			return nil, &Fault{ErrCode: 40014, ErrMsg: "不合法的access_token"}
+		}
+	}
+	return h(r)
+}
+
+func (s *Server) getToken(r *http.Request) (interface{}, *Fault) {
+	q := r.URL.Query()
+	if q.Get("appkey") != s.appKey || q.Get("appsecret") != s.appSecret {
+		return nil, &Fault{ErrCode: 40089, ErrMsg: "不合法的appKey或appSecret"}
+	}
+	s.seq++
+	token := fmt.Sprintf("token-%d", s.seq)
+	s.tokens[token] = time.Now().Add(s.tokenTTL)
+	return map[string]interface{}{"access_token": token, "expires_in": int(s.tokenTTL.Seconds())}, nil
+}
+
+func (s *Server) getJSAPITicket(r *http.Request) (interface{}, *Fault) {
+	s.seq++
+	return map[string]interface{}{"ticket": fmt.Sprintf("ticket-%d", s.seq), "expires_in": 7200}, nil
+}
+
+func (s *Server) getOrgUserCount(r *http.Request) (interface{}, *Fault) {
+	onlyActive := r.URL.Query().Get("onlyActive") == "1"
+	count := 0
+	for _, u := range s.users {
+		if !onlyActive || u.Active {
+			count++
+		}
+	}
+	return map[string]interface{}{"count": count}, nil
+}
+
+func (s *Server) getUserByUnionID(r *http.Request) (interface{}, *Fault) {
+	var req struct {
+		UnionID string `json:"unionid"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	for _, u := range s.users {
+		if u.UnionID == req.UnionID {
+			return result(map[string]interface{}{"contact_type": 0, "userid": u.UserID}), nil
+		}
+	}
+	return nil, userNotExist()
+}
+
+func (s *Server) getUser(r *http.Request) (interface{}, *Fault) {
+	var req struct {
+		UserID string `json:"userid"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	u, ok := s.users[req.UserID]
+	if !ok {
+		return nil, userNotExist()
+	}
+	return result(s.userJSON(u)), nil
+}
+
+func (s *Server) getUserByMobile(r *http.Request) (interface{}, *Fault) {
+	var req struct {
+		Mobile string `json:"mobile"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	for _, u := range s.users {
+		if u.Mobile != "" && u.Mobile == req.Mobile {
+			return result(map[string]interface{}{"userid": u.UserID}), nil
+		}
+	}
+	return nil, userNotExist()
+}
+
+func (s *Server) listDeptUsers(r *http.Request) (interface{}, *Fault) {
+	var req struct {
+		DeptID int `json:"dept_id"`
+		Cursor int `json:"cursor"`
+		Size   int `json:"size"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	if _, ok := s.depts[req.DeptID]; !ok {
+		return nil, deptNotExist()
+	}
+	if req.Size <= 0 || req.Size > 100 {
+		return nil, &Fault{ErrCode: 40035, ErrMsg: "不合法的参数:size"}
+	}
+
+	var members []map[string]interface{}
+	for _, id := range s.sortedUserIDs() {
+		u := s.users[id]
+		if containsInt(u.DeptIDs, req.DeptID) {
+			m := s.userJSON(u)
+			m["leader"] = s.isManager(req.DeptID, u.UserID)
+			members = append(members, m)
+		}
+	}
+	page := map[string]interface{}{"has_more": false, "list": []map[string]interface{}{}}
+	if req.Cursor < len(members) {
+		end := req.Cursor + req.Size
+		if end < len(members) {
+			page["has_more"] = true
+			page["next_cursor"] = end
+		} else {
+			end = len(members)
+		}
+		page["list"] = members[req.Cursor:end]
+	}
+	return result(page), nil
+}
+
+func (s *Server) getDepartment(r *http.Request) (interface{}, *Fault) {
+	raw := r.URL.Query().Get("id")
+	if raw == "" {
+		return nil, &Fault{ErrCode: 40035, ErrMsg: "缺少参数 id"}
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, &Fault{ErrCode: 40035, ErrMsg: "不合法的参数 id"}
+	}
+	d, ok := s.depts[id]
+	if !ok {
+		return nil, deptNotExist()
+	}
+	ret := map[string]interface{}{"errcode": 0, "errmsg": "ok", "id": d.ID, "name": d.Name, "order": d.ID}
+	if d.ID != RootDeptID {
+		ret["parentid"] = d.ParentID
+	}
+	if len(d.ManagerUserIDs) > 0 {
+		ret["deptManagerUseridList"] = strings.Join(d.ManagerUserIDs, "|")
+	}
+	return ret, nil
+}
+
+func (s *Server) getDepartmentV2(r *http.Request) (interface{}, *Fault) {
+	d, f := s.decodeDept(r)
+	if f != nil {
+		return nil, f
+	}
+	ret := map[string]interface{}{"dept_id": d.ID, "name": d.Name, "order": d.ID}
+	if d.ID != RootDeptID {
+		ret["parent_id"] = d.ParentID
+	}
+	if len(d.ManagerUserIDs) > 0 {
+		ret["dept_manager_userid_list"] = d.ManagerUserIDs
+	}
+	return result(ret), nil
+}
+
+func (s *Server) listParentByUser(r *http.Request) (interface{}, *Fault) {
+	var req struct {
+		UserID string `json:"userid"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	u, ok := s.users[req.UserID]
+	if !ok {
+		return nil, userNotExist()
+	}
+	parents := make([]map[string]interface{}, 0, len(u.DeptIDs))
+	for _, id := range u.DeptIDs {
+		parents = append(parents, map[string]interface{}{"parent_dept_id_list": s.ancestors(id)})
+	}
+	return result(map[string]interface{}{"parent_list": parents}), nil
+}
+
+func (s *Server) listParentByDept(r *http.Request) (interface{}, *Fault) {
+	d, f := s.decodeDept(r)
+	if f != nil {
+		return nil, f
+	}
+	return result(map[string]interface{}{"parent_id_list": s.ancestors(d.ID)}), nil
+}
+
+func (s *Server) listSubDeptID(r *http.Request) (interface{}, *Fault) {
+	d, f := s.decodeDept(r)
+	if f != nil {
+		return nil, f
+	}
+	ids := []int{}
+	for id, sub := range s.depts {
+		if id != RootDeptID && sub.ParentID == d.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return result(map[string]interface{}{"dept_id_list": ids}), nil
+}
+
+func (s *Server) createProcessInstance(r *http.Request) (interface{}, *Fault) {
+	var req struct {
+		ProcessCode      string      `json:"process_code"`
+		OriginatorUserID string      `json:"originator_user_id"`
+		DeptID           string      `json:"dept_id"`
+		FormValues       []FormValue `json:"form_component_values"`
+		Approvers        []struct {
+			UserIDs []string `json:"user_ids"`
+		} `json:"approvers_v2"`
+		CCList string `json:"cc_list"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	if req.ProcessCode == "" {
+		return nil, &Fault{ErrCode: 40035, ErrMsg: "缺少参数 process_code"}
+	}
+	originator, ok := s.users[req.OriginatorUserID]
+	if !ok {
+		return nil, userNotExist()
+	}
+
+	s.seq++
+	pi := &ProcessInstance{
+		ID:               fmt.Sprintf("proc-%d", s.seq),
+		ProcessCode:      req.ProcessCode,
+		Title:            originator.Name + "提交的审批",
+		OriginatorUserID: originator.UserID,
+		OriginatorDeptID: req.DeptID,
+		Status:           "RUNNING",
+		FormValues:       req.FormValues,
+		CreateTime:       time.Now(),
+	}
+	for _, a := range req.Approvers {
+		pi.ApproverUserIDs = append(pi.ApproverUserIDs, a.UserIDs...)
+	}
+	if req.CCList != "" {
+		pi.CCUserIDs = strings.Split(req.CCList, ",")
+	}
+	s.instances[pi.ID] = pi
+	return map[string]interface{}{"errcode": 0, "errmsg": "ok", "process_instance_id": pi.ID}, nil
+}
+
+func (s *Server) getProcessInstance(r *http.Request) (interface{}, *Fault) {
+	var req struct {
+		ID string `json:"process_instance_id"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	pi, ok := s.instances[req.ID]
+	if !ok {
+		return nil, &Fault{ErrCode: 400, ErrMsg: "审批实例不存在"}
+	}
+	ret := map[string]interface{}{
+		"title":                 pi.Title,
+		"create_time":           formatTime(pi.CreateTime),
+		"originator_userid":     pi.OriginatorUserID,
+		"originator_dept_id":    pi.OriginatorDeptID,
+		"status":                pi.Status,
+		"approver_userids":      pi.ApproverUserIDs,
+		"cc_userids":            pi.CCUserIDs,
+		"result":                pi.Result,
+		"business_id":           pi.ID,
+		"form_component_values": pi.FormValues,
+	}
+	if !pi.FinishTime.IsZero() {
+		ret["finish_time"] = formatTime(pi.FinishTime)
+	}
+	return map[string]interface{}{"errcode": 0, "errmsg": "ok", "process_instance": ret}, nil
+}
+
+func (s *Server) listEmployees(r *http.Request) (interface{}, *Fault) {
+	var req struct {
+		UserIDs string `json:"userid_list"`
+		Fields  string `json:"field_filter_list"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	ids, f := splitUserIDs(req.UserIDs, MaxHRMEmployees)
+	if f != nil {
+		return nil, f
+	}
+	var filter []string
+	if req.Fields != "" {
+		filter = strings.Split(req.Fields, ",")
+	}
+
+	list := []map[string]interface{}{}
+	for _, id := range ids {
+		e, ok := s.employees[id]
+		if !ok {
+			continue
+		}
+		codes := make([]string, 0, len(e.Fields))
+		for code := range e.Fields {
+			if filter == nil || containsString(filter, code) {
+				codes = append(codes, code)
+			}
+		}
+		sort.Strings(codes)
+		fields := make([]map[string]interface{}, 0, len(codes))
+		for _, code := range codes {
+			fields = append(fields, map[string]interface{}{
+				"field_code":       code,
+				"field_name":       code,
+				"group_id":         strings.SplitN(code, "-", 2)[0],
+				"field_value_list": []map[string]interface{}{{"value": e.Fields[code], "label": e.Labels[code], "item_index": 0}},
+			})
+		}
+		list = append(list, map[string]interface{}{"userid": e.UserID, "field_data_list": fields})
+	}
+	return map[string]interface{}{"errcode": 0, "errmsg": "ok", "result": list}, nil
+}
+
+// queryEmployees 按条件分页查询员工userid，size最大50
+func (s *Server) queryEmployees(match func(e *Employee, status []string) bool) handler {
+	return func(r *http.Request) (interface{}, *Fault) {
+		var req struct {
+			StatusList string `json:"status_list"`
+			Offset     int    `json:"offset"`
+			Size       int    `json:"size"`
+		}
+		if f := decode(r, &req); f != nil {
+			return nil, f
+		}
+		if req.Size <= 0 || req.Size > 50 {
+			return nil, &Fault{ErrCode: 40035, ErrMsg: "不合法的参数:size"}
+		}
+		status := strings.Split(req.StatusList, ",")
+
+		ids := []string{}
+		for _, id := range s.sortedEmployeeIDs() {
+			if match(s.employees[id], status) {
+				ids = append(ids, id)
+			}
+		}
+		page := map[string]interface{}{"data_list": []string{}}
+		if req.Offset < len(ids) {
+			end := req.Offset + req.Size
+			if end < len(ids) {
+				page["next_cursor"] = end
+			} else {
+				end = len(ids)
+			}
+			page["data_list"] = ids[req.Offset:end]
+		}
+		return result(page), nil
+	}
+}
+
+func (s *Server) listDimission(r *http.Request) (interface{}, *Fault) {
+	var req struct {
+		UserIDs string `json:"userid_list"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	ids, f := splitUserIDs(req.UserIDs, MaxHRMDimission)
+	if f != nil {
+		return nil, f
+	}
+	list := []map[string]interface{}{}
+	for _, id := range ids {
+		e, ok := s.employees[id]
+		if !ok || e.Dimission == nil {
+			continue
+		}
+		d := e.Dimission
+		list = append(list, map[string]interface{}{
+			"userid":        e.UserID,
+			"last_work_day": d.LastWorkDay.UnixNano() / 1e6,
+			"reason_type":   d.ReasonType,
+			"reason_memo":   d.ReasonMemo,
+			"pre_status":    d.PreStatus,
+			"status":        d.Status,
+		})
+	}
+	return map[string]interface{}{"errcode": 0, "errmsg": "ok", "result": list}, nil
+}
+
+func (s *Server) sortedEmployeeIDs() []string {
+	ids := make([]string, 0, len(s.employees))
+	for id := range s.employees {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// splitUserIDs 解析逗号分隔的userid列表，超过max个时返回参数错误
+func splitUserIDs(raw string, max int) ([]string, *Fault) {
+	if raw == "" {
+		return nil, &Fault{ErrCode: 40035, ErrMsg: "缺少参数 userid_list"}
+	}
+	ids := strings.Split(raw, ",")
+	if len(ids) > max {
+		return nil, &Fault{ErrCode: 40035, ErrMsg: fmt.Sprintf("不合法的参数:userid_list最多%d个", max)}
+	}
+	return ids, nil
+}
+
+func (s *Server) decodeDept(r *http.Request) (*Department, *Fault) {
+	var req struct {
+		DeptID int `json:"dept_id"`
+	}
+	if f := decode(r, &req); f != nil {
+		return nil, f
+	}
+	d, ok := s.depts[req.DeptID]
+	if !ok {
+		return nil, deptNotExist()
+	}
+	return d, nil
+}
+
+// ancestors 返回部门自身及其所有上级部门ID，由近及远
+func (s *Server) ancestors(id int) []int {
+	ids := []int{}
+	for d, ok := s.depts[id]; ok; d, ok = s.depts[d.ParentID] {
+		ids = append(ids, d.ID)
+		if d.ID == RootDeptID {
+			break
+		}
+	}
+	return ids
+}
+
+func (s *Server) isManager(deptID int, userID string) bool {
+	d, ok := s.depts[deptID]
+	return ok && containsString(d.ManagerUserIDs, userID)
+}
+
+func (s *Server) sortedUserIDs() []string {
+	ids := make([]string, 0, len(s.users))
+	for id := range s.users {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *Server) userJSON(u *User) map[string]interface{} {
+	leaders := make([]map[string]interface{}, 0, len(u.DeptIDs))
+	for _, id := range u.DeptIDs {
+		leaders = append(leaders, map[string]interface{}{"dept_id": id, "leader": s.isManager(id, u.UserID)})
+	}
+	return map[string]interface{}{
+		"userid":         u.UserID,
+		"unionid":        u.UnionID,
+		"name":           u.Name,
+		"mobile":         u.Mobile,
+		"title":          u.Title,
+		"email":          u.Email,
+		"job_number":     u.JobNumber,
+		"dept_id_list":   u.DeptIDs,
+		"active":         u.Active,
+		"admin":          u.Admin,
+		"boss":           u.Boss,
+		"leader_in_dept": leaders,
+	}
+}
+
+func decode(r *http.Request, v interface{}) *Fault {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return &Fault{ErrCode: 40035, ErrMsg: "不合法的参数:" + err.Error()}
+	}
+	return nil
+}
+
+func result(v interface{}) map[string]interface{} {
+	return map[string]interface{}{"errcode": 0, "errmsg": "ok", "request_id": "dingtalktest", "result": v}
+}
+
+func userNotExist() *Fault {
+	return &Fault{ErrCode: 60121, ErrMsg: "找不到该用户"}
+}
+
+func deptNotExist() *Fault {
+	return &Fault{ErrCode: 60003, ErrMsg: "部门不存在"}
+}
+
+func writeFault(w http.ResponseWriter, f *Fault) {
+	status := f.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= http.StatusMultipleChoices && f.ErrCode == 0 {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(f.ErrMsg))
+		return
+	}
+	ret := map[string]interface{}{"errcode": f.ErrCode, "errmsg": f.ErrMsg, "request_id": "dingtalktest"}
+	if f.SubCode != "" {
+		ret["sub_code"] = f.SubCode
+		ret["sub_msg"] = f.SubMsg
+	}
+	writeJSON(w, status, ret)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func formatTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func containsInt(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
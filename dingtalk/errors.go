@@ -1,6 +1,12 @@
 package dingtalk
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
 
 // DingtalkErr 钉钉错误信息
 type DingtalkErr struct {
@@ -8,6 +14,10 @@ type DingtalkErr struct {
 	ErrorCode    int    `json:"errcode,omitempty"`
 	SubCode      string `json:"sub_code,omitempty"`
 	SubMessage   string `json:"sub_msg,omitempty"`
+
+	RequestID  string `json:"-"` // 钉钉返回的request_id，反馈问题时使用
+	Path       string `json:"-"` // 请求的接口路径
+	StatusCode int    `json:"-"` // HTTP状态码
 }
 
 // GotErr 返回response中的错误
@@ -37,12 +47,183 @@ func (de *DingtalkErr) IsAccessTokenExpired() bool {
 	return false
 }
 
+// Is 错误码（及子错误码）相同即视为同一错误，如errors.Is(err, &DingtalkErr{ErrorCode: 60121})
+func (de *DingtalkErr) Is(target error) bool {
+	t, ok := target.(*DingtalkErr)
+	if !ok {
+		return false
+	}
+	return de.ErrorCode == t.ErrorCode && (t.SubCode == "" || de.SubCode == t.SubCode)
+}
+
+// Unwrap 返回错误所属的分类，如ErrNotFound、ErrRateLimited，未归类时返回nil
+func (de *DingtalkErr) Unwrap() error {
+	if de.IsAccessTokenExpired() {
+		return ErrAccessTokenExpired
+	}
+	code := strconv.Itoa(de.ErrorCode)
+	if de.ErrorCode == AuthenticationAbnormal && de.SubCode != "" {
+		code = de.SubCode
+	}
+	return errorCodeCategories[code]
+}
+
+// GatewayErr 新版服务端API（api.dingtalk.com）的错误信息
+type GatewayErr struct {
+	StatusCode int    `json:"-"`
+	Path       string `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RequestID  string `json:"requestid"`
+}
+
+// Error error的实现
+func (ge *GatewayErr) Error() string {
+	return fmt.Sprintf("[%d %s]: %s", ge.StatusCode, ge.Code, ge.Message)
+}
+
+// IsAccessTokenExpired access_token是否过期
+func (ge *GatewayErr) IsAccessTokenExpired() bool {
+	return ge.Code == GatewayInvalidAuthentication
+}
+
+// Is 错误码相同即视为同一错误
+func (ge *GatewayErr) Is(target error) bool {
+	t, ok := target.(*GatewayErr)
+	return ok && ge.Code == t.Code
+}
+
+// Unwrap 返回错误所属的分类，未归类时返回nil
+func (ge *GatewayErr) Unwrap() error {
+	if ge.IsAccessTokenExpired() {
+		return ErrAccessTokenExpired
+	}
+	code := strings.ToLower(ge.Code)
+	switch {
+	case strings.Contains(code, "qpslimit") || strings.Contains(code, "throttling"):
+		return ErrRateLimited
+	case strings.Contains(code, "notexist") || strings.Contains(code, "notfound"):
+		return ErrNotFound
+	case strings.HasPrefix(code, "forbidden"):
+		return ErrPermissionDenied
+	case strings.HasPrefix(code, "invalid") || strings.HasPrefix(code, "missing") || strings.HasPrefix(code, "paramerror"):
+		return ErrInvalidParam
+	}
+	return httpStatusCategory(ge.StatusCode)
+}
+
+// HTTPError 服务端返回了非预期的HTTP状态码
+type HTTPError struct {
+	StatusCode int
+	Path       string
+	Body       []byte
+}
+
+// Error error的实现
+func (he *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected http status %d: %s", he.StatusCode, he.Body)
+}
+
+// Unwrap 返回HTTP状态码所属的分类，未归类时返回nil
+func (he *HTTPError) Unwrap() error {
+	return httpStatusCategory(he.StatusCode)
+}
+
+func httpStatusCategory(status int) error {
+	switch status {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusForbidden:
+		return ErrPermissionDenied
+	case http.StatusBadRequest:
+		return ErrInvalidParam
+	}
+	return nil
+}
+
+// 错误分类，可通过errors.Is判断
+var (
+	ErrAccessTokenExpired = errors.New("dingtalk: access token expired")
+	ErrNotFound           = errors.New("dingtalk: not found")
+	ErrUserNotExist       = fmt.Errorf("%w: user not exist", ErrNotFound)
+	ErrPermissionDenied   = errors.New("dingtalk: permission denied")
+	ErrRateLimited        = errors.New("dingtalk: rate limited")
+	ErrInvalidParam       = errors.New("dingtalk: invalid parameter")
+)
+
+// errorCodeCategories 错误码所属的分类 https://developers.dingtalk.com/document/app/server-api-error-codes-1
+var errorCodeCategories = map[string]error{
+	// 限流
+	"90002": ErrRateLimited,
+	"90005": ErrRateLimited,
+	"90006": ErrRateLimited,
+	"90008": ErrRateLimited,
+	"90010": ErrRateLimited,
+	"90014": ErrRateLimited,
+	"90018": ErrRateLimited,
+	"90019": ErrRateLimited,
+	// 用户不存在
+	"33012": ErrUserNotExist,
+	"60111": ErrUserNotExist,
+	"60121": ErrUserNotExist,
+	// 资源不存在
+	"60003": ErrNotFound,
+	"60123": ErrNotFound,
+	// 无权限
+	"50001": ErrPermissionDenied,
+	"50002": ErrPermissionDenied,
+	"50004": ErrPermissionDenied,
+	"60011": ErrPermissionDenied,
+	"60020": ErrPermissionDenied,
+	// 参数错误
+	"40035":  ErrInvalidParam,
+	"400002": ErrInvalidParam,
+	"60001":  ErrInvalidParam,
+	"60103":  ErrInvalidParam,
+}
+
+// IsNotFound 资源（用户、部门、待办等）不存在
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsUserNotExist 用户不存在
+func IsUserNotExist(err error) bool {
+	return errors.Is(err, ErrUserNotExist)
+}
+
+// IsPermissionDenied 应用无权限或数据不在授权范围内
+func IsPermissionDenied(err error) bool {
+	return errors.Is(err, ErrPermissionDenied)
+}
+
+// IsRateLimited 触发钉钉限流
+func IsRateLimited(err error) bool {
+	return errors.Is(err, ErrRateLimited)
+}
+
+// IsInvalidParam 请求参数错误
+func IsInvalidParam(err error) bool {
+	return errors.Is(err, ErrInvalidParam)
+}
+
+func isAccessTokenExpired(err error) bool {
+	return errors.Is(err, ErrAccessTokenExpired)
+}
+
 const (
 	// https://ding-doc.dingtalk.com/document#/org-dev-guide/server-api-error-codes
+	// SystemBusy 系统繁忙
+	SystemBusy = -1
 	// AuthenticationAbnormal 鉴权异常
 	AuthenticationAbnormal = 88
 	// InvalidAccessToken 获取access_token时Secret错误，或者access_token无效
 	InvalidAccessToken = "40001"
 	EmptyAccessToken   = "40000"
 	IllegalAccessToken = "40014"
+
+	// GatewayInvalidAuthentication 新版服务端API的access_token无效
+	GatewayInvalidAuthentication = "InvalidAuthentication"
 )
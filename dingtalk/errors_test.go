@@ -0,0 +1,50 @@
+package dingtalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDingtalkErr_Classification(t *testing.T) {
+	userNotExist := fmt.Errorf("sync user: %w", &DingtalkErr{ErrorCode: 60121, ErrorMessage: "找不到该用户"})
+	assert.True(t, IsUserNotExist(userNotExist))
+	assert.True(t, IsNotFound(userNotExist))
+	assert.False(t, IsPermissionDenied(userNotExist))
+	assert.True(t, errors.Is(userNotExist, &DingtalkErr{ErrorCode: 60121}))
+	assert.False(t, errors.Is(userNotExist, &DingtalkErr{ErrorCode: 60003}))
+
+	throttled := &DingtalkErr{ErrorCode: AuthenticationAbnormal, SubCode: "90018"}
+	assert.True(t, IsRateLimited(throttled))
+	assert.True(t, errors.Is(throttled, &DingtalkErr{ErrorCode: AuthenticationAbnormal, SubCode: "90018"}))
+
+	assert.True(t, IsPermissionDenied(&DingtalkErr{ErrorCode: 60011}))
+	assert.True(t, IsInvalidParam(&DingtalkErr{ErrorCode: 40035}))
+	assert.True(t, errors.Is(&DingtalkErr{ErrorCode: 40014}, ErrAccessTokenExpired))
+	assert.Nil(t, (&DingtalkErr{ErrorCode: 12345}).Unwrap())
+
+	assert.True(t, IsNotFound(&GatewayErr{StatusCode: http.StatusNotFound, Code: "taskNotExist"}))
+	assert.True(t, IsRateLimited(&GatewayErr{StatusCode: http.StatusTooManyRequests, Code: "Throttling"}))
+	assert.True(t, IsPermissionDenied(&HTTPError{StatusCode: http.StatusForbidden}))
+}
+
+func TestClient_ErrorAnnotation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errcode":60121,"errmsg":"找不到该用户","request_id":"req-1"}`))
+	}))
+	defer srv.Close()
+
+	ding := NewClient(Option{AppKey: "key", AppSecret: "secret"}, WithBaseURL(srv.URL))
+	_, _, err := ding.GetUserByUnionID(context.Background(), &RequestGetByUnionID{UnionID: "union"})
+	var de *DingtalkErr
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "req-1", de.RequestID)
+	assert.Equal(t, "/topapi/user/getbyunionid", de.Path)
+	assert.Equal(t, http.StatusOK, de.StatusCode)
+	assert.True(t, IsUserNotExist(err))
+}
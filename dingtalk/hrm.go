@@ -0,0 +1,218 @@
+package dingtalk
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/jacexh/requests"
+)
+
+type (
+	// HRMFieldValue 花名册字段值
+	HRMFieldValue struct {
+		Value     string `json:"value"`
+		Label     string `json:"label"`
+		ItemIndex int    `json:"item_index"`
+	}
+
+	// HRMField 花名册字段
+	HRMField struct {
+		FieldCode      string           `json:"field_code"`
+		FieldName      string           `json:"field_name"`
+		GroupID        string           `json:"group_id"`
+		FieldValueList []*HRMFieldValue `json:"field_value_list"`
+	}
+
+	// HRMEmployee 员工花名册
+	HRMEmployee struct {
+		UserID        string      `json:"userid"`
+		FieldDataList []*HRMField `json:"field_data_list"`
+	}
+
+	ResponseListHRMEmployees struct {
+		BasicResponse `json:",inline"`
+		Result        []*HRMEmployee `json:"result"`
+	}
+
+	// HRMUserIDPage 智能人事分页查询的员工userid列表，NextCursor为0表示没有更多数据
+	HRMUserIDPage struct {
+		DataList   []string `json:"data_list"`
+		NextCursor int      `json:"next_cursor,omitempty"`
+	}
+
+	ResponseHRMUserIDPage struct {
+		BasicResponse `json:",inline"`
+		Result        *HRMUserIDPage `json:"result"`
+	}
+
+	HRMDept struct {
+		DeptID   int    `json:"dept_id"`
+		DeptPath string `json:"dept_path"`
+	}
+
+	// HRMDimission 员工离职信息
+	HRMDimission struct {
+		UserID          string        `json:"userid"`
+		LastWorkDay     UnixTimestamp `json:"last_work_day"`
+		DeptList        []*HRMDept    `json:"dept_list"`
+		MainDeptID      int           `json:"main_dept_id"`
+		MainDeptName    string        `json:"main_dept_name"`
+		ReasonMemo      string        `json:"reason_memo"`
+		ReasonType      int           `json:"reason_type"`
+		VoluntaryReason []string      `json:"voluntary_reason"`
+		PassiveReason   []string      `json:"passive_reason"`
+		PreStatus       int           `json:"pre_status"` // 离职前工作状态：1待入职 2试用期 3正式
+		HandoverUserID  string        `json:"handover_userid"`
+		Status          int           `json:"status"` // 1待离职 2已离职
+	}
+
+	ResponseListHRMDimission struct {
+		BasicResponse `json:",inline"`
+		Result        []*HRMDimission `json:"result"`
+	}
+)
+
+const (
+	// HRMMaxEmployees 花名册接口单次查询的最大用户数
+	HRMMaxEmployees = 100
+	// HRMMaxDimission 离职信息接口单次查询的最大用户数
+	HRMMaxDimission = 50
+)
+
+// 花名册常用字段 https://developers.dingtalk.com/document/app/roster-field-information
+const (
+	HRMFieldName                   = "sys00-name"
+	HRMFieldMobile                 = "sys00-mobile"
+	HRMFieldJobNumber              = "sys00-jobNumber"
+	HRMFieldMainDept               = "sys00-mainDept"
+	HRMFieldPosition               = "sys00-position"
+	HRMFieldConfirmJoinTime        = "sys00-confirmJoinTime"
+	HRMFieldEmployeeType           = "sys01-employeeType"
+	HRMFieldEmployeeStatus         = "sys01-employeeStatus"
+	HRMFieldProbationPeriodType    = "sys01-probationPeriodType"
+	HRMFieldRegularTime            = "sys01-regularTime"
+	HRMFieldPositionLevel          = "sys01-positionLevel"
+	HRMFieldContractCompanyName    = "sys05-contractCompanyName"
+	HRMFieldContractType           = "sys05-contractType"
+	HRMFieldFirstContractStartTime = "sys05-firstContractStartTime"
+	HRMFieldFirstContractEndTime   = "sys05-firstContractEndTime"
+	HRMFieldNowContractStartTime   = "sys05-nowContractStartTime"
+	HRMFieldNowContractEndTime     = "sys05-nowContractEndTime"
+	HRMFieldContractPeriodType     = "sys05-contractPeriodType"
+	HRMFieldContractRenewCount     = "sys05-contractRenewCount"
+)
+
+// Field 返回字段的第一个取值
+func (emp *HRMEmployee) Field(code string) (*HRMFieldValue, bool) {
+	for _, f := range emp.FieldDataList {
+		if f.FieldCode == code && len(f.FieldValueList) > 0 {
+			return f.FieldValueList[0], true
+		}
+	}
+	return nil, false
+}
+
+// Fields 以字段code为key返回字段值（选项类字段取label）
+func (emp *HRMEmployee) Fields() map[string]string {
+	fields := make(map[string]string, len(emp.FieldDataList))
+	for _, f := range emp.FieldDataList {
+		if len(f.FieldValueList) == 0 {
+			continue
+		}
+		v := f.FieldValueList[0]
+		if v.Label != "" {
+			fields[f.FieldCode] = v.Label
+		} else {
+			fields[f.FieldCode] = v.Value
+		}
+	}
+	return fields
+}
+
+// ListHRMEmployees 批量获取员工花名册字段，超过100人的用户列表会被自动拆分为多次请求，fieldCodes为空时返回全部字段 https://developers.dingtalk.com/document/app/intelligent-personnel-obtain-employee-roster-information
+func (ding *Client) ListHRMEmployees(ctx context.Context, userIDs, fieldCodes []string) ([]*HRMEmployee, *http.Response, error) {
+	var employees []*HRMEmployee
+	var res *http.Response
+
+	for _, users := range splitUserIDs(userIDs, HRMMaxEmployees) {
+		ret := new(ResponseListHRMEmployees)
+		body := requests.Any{"userid_list": strings.Join(users, ","), "agentid": ding.Option().AgentID}
+		if len(fieldCodes) > 0 {
+			body["field_filter_list"] = strings.Join(fieldCodes, ",")
+		}
+		var err error
+		err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+			res, _, err = ding.post(
+				ctx,
+				ding.url+"/topapi/smartwork/hrm/employee/v2/list",
+				requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: body},
+				UnmarshalAndParseError(ret),
+			)
+			return err
+		})
+		if err != nil {
+			return employees, res, err
+		}
+		employees = append(employees, ret.Result...)
+	}
+	return employees, res, nil
+}
+
+// QueryPreEntryEmployees 分页查询待入职员工，size最大50 https://developers.dingtalk.com/document/app/intelligent-personnel-query-the-list-of-employees-to-be-hired
+func (ding *Client) QueryPreEntryEmployees(ctx context.Context, offset, size int) (*HRMUserIDPage, *http.Response, error) {
+	return ding.queryHRMUserIDs(ctx, "/topapi/smartwork/hrm/employee/querypreentry", map[string]interface{}{"offset": offset, "size": size})
+}
+
+// QueryOnJobEmployees 分页查询在职员工，statusList为员工状态：2试用期 3正式 5待离职 -1无状态 https://developers.dingtalk.com/document/app/intelligent-personnel-query-the-list-of-on-the-job-employees-of-the
+func (ding *Client) QueryOnJobEmployees(ctx context.Context, statusList []string, offset, size int) (*HRMUserIDPage, *http.Response, error) {
+	return ding.queryHRMUserIDs(ctx, "/topapi/smartwork/hrm/employee/queryonjob", map[string]interface{}{"status_list": strings.Join(statusList, ","), "offset": offset, "size": size})
+}
+
+// QueryDimissionEmployees 分页查询离职员工，size最大50 https://developers.dingtalk.com/document/app/intelligent-personnel-query-the-list-of-departing-employees
+func (ding *Client) QueryDimissionEmployees(ctx context.Context, offset, size int) (*HRMUserIDPage, *http.Response, error) {
+	return ding.queryHRMUserIDs(ctx, "/topapi/smartwork/hrm/employee/querydimission", map[string]interface{}{"offset": offset, "size": size})
+}
+
+func (ding *Client) queryHRMUserIDs(ctx context.Context, path string, body map[string]interface{}) (*HRMUserIDPage, *http.Response, error) {
+	ret := new(ResponseHRMUserIDPage)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.url+path,
+			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: body},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return ret.Result, res, err
+}
+
+// ListHRMDimission 批量获取员工离职信息，超过50人的用户列表会被自动拆分为多次请求 https://developers.dingtalk.com/document/app/intelligent-personnel-obtain-employee-departure-information
+func (ding *Client) ListHRMDimission(ctx context.Context, userIDs []string) ([]*HRMDimission, *http.Response, error) {
+	var dimissions []*HRMDimission
+	var res *http.Response
+
+	for _, users := range splitUserIDs(userIDs, HRMMaxDimission) {
+		ret := new(ResponseListHRMDimission)
+		body := requests.Any{"userid_list": strings.Join(users, ",")}
+		var err error
+		err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+			res, _, err = ding.post(
+				ctx,
+				ding.url+"/topapi/smartwork/hrm/employee/listdimission",
+				requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: body},
+				UnmarshalAndParseError(ret),
+			)
+			return err
+		})
+		if err != nil {
+			return dimissions, res, err
+		}
+		dimissions = append(dimissions, ret.Result...)
+	}
+	return dimissions, res, nil
+}
@@ -0,0 +1,126 @@
+package dingtalk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wosai/go-clients/dingtalk/dingtalktest"
+)
+
+// addHRMEmployees 写入待入职、试用期、正式、待离职及已离职员工各一名
+func addHRMEmployees() {
+	fake.AddEmployees(
+		dingtalktest.Employee{UserID: "hrm-pre", Status: 1},
+		dingtalktest.Employee{UserID: "hrm-probation", Status: 2},
+		dingtalktest.Employee{
+			UserID: "hrm-regular",
+			Status: 3,
+			Fields: map[string]string{HRMFieldName: "赵六", HRMFieldJobNumber: "A001", HRMFieldEmployeeType: "1"},
+			Labels: map[string]string{HRMFieldEmployeeType: "全职"},
+		},
+		dingtalktest.Employee{UserID: "hrm-leaving", Status: 5},
+		dingtalktest.Employee{UserID: "hrm-left", Status: 3, Dimission: &dingtalktest.Dimission{
+			LastWorkDay: time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC),
+			ReasonType:  1,
+			ReasonMemo:  "家庭原因",
+			PreStatus:   3,
+			Status:      2,
+		}},
+	)
+}
+
+func TestClient_QueryHRMEmployees(t *testing.T) {
+	addHRMEmployees()
+	ding := DingClient
+	ctx := context.Background()
+
+	page, _, err := ding.QueryPreEntryEmployees(ctx, 0, 50)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hrm-pre"}, page.DataList)
+	assert.Equal(t, 0, page.NextCursor)
+
+	page, _, err = ding.QueryOnJobEmployees(ctx, []string{"2", "5"}, 0, 50)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hrm-leaving", "hrm-probation"}, page.DataList)
+
+	// 按next_cursor翻页直到没有更多数据
+	var ids []string
+	offset := 0
+	for {
+		page, _, err = ding.QueryOnJobEmployees(ctx, []string{"2", "3", "5"}, offset, 1)
+		assert.Nil(t, err)
+		ids = append(ids, page.DataList...)
+		if page.NextCursor == 0 {
+			break
+		}
+		offset = page.NextCursor
+	}
+	assert.Equal(t, []string{"hrm-leaving", "hrm-probation", "hrm-regular"}, ids)
+
+	page, _, err = ding.QueryDimissionEmployees(ctx, 0, 50)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hrm-left"}, page.DataList)
+
+	dimissions, _, err := ding.ListHRMDimission(ctx, page.DataList)
+	assert.Nil(t, err)
+	assert.Len(t, dimissions, 1)
+	assert.Equal(t, "家庭原因", dimissions[0].ReasonMemo)
+	assert.Equal(t, 2, dimissions[0].Status)
+	assert.True(t, time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC).Equal(dimissions[0].LastWorkDay.Time()))
+
+	_, _, err = ding.QueryPreEntryEmployees(ctx, 0, 100)
+	assert.True(t, IsInvalidParam(err))
+}
+
+func TestClient_ListHRMEmployees(t *testing.T) {
+	addHRMEmployees()
+	ding := DingClient
+	ctx := context.Background()
+
+	employees, _, err := ding.ListHRMEmployees(ctx, []string{"hrm-regular"}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, employees, 1)
+	emp := employees[0]
+	assert.Equal(t, "hrm-regular", emp.UserID)
+	// 选项类字段取label
+	assert.Equal(t, map[string]string{HRMFieldName: "赵六", HRMFieldJobNumber: "A001", HRMFieldEmployeeType: "全职"}, emp.Fields())
+	value, ok := emp.Field(HRMFieldEmployeeType)
+	assert.True(t, ok)
+	assert.Equal(t, "1", value.Value)
+	_, ok = emp.Field(HRMFieldMobile)
+	assert.False(t, ok)
+
+	employees, _, err = ding.ListHRMEmployees(ctx, []string{"hrm-regular"}, []string{HRMFieldJobNumber})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{HRMFieldJobNumber: "A001"}, employees[0].Fields())
+}
+
+func TestClient_ListHRMEmployees_Batch(t *testing.T) {
+	addHRMEmployees()
+	ding := DingClient
+	ctx := context.Background()
+
+	// 超过接口上限的用户列表拆分为多次请求
+	userIDs := make([]string, 0, 2*HRMMaxEmployees+1)
+	for i := 0; i < 2*HRMMaxEmployees; i++ {
+		userIDs = append(userIDs, fmt.Sprintf("missing-%d", i))
+	}
+	userIDs = append(userIDs, "hrm-regular")
+
+	before := fake.Requests("/topapi/smartwork/hrm/employee/v2/list")
+	employees, _, err := ding.ListHRMEmployees(ctx, userIDs, nil)
+	assert.Nil(t, err)
+	assert.Len(t, employees, 1)
+	assert.Equal(t, "hrm-regular", employees[0].UserID)
+	assert.Equal(t, 3, fake.Requests("/topapi/smartwork/hrm/employee/v2/list")-before)
+
+	before = fake.Requests("/topapi/smartwork/hrm/employee/listdimission")
+	dimissions, _, err := ding.ListHRMDimission(ctx, append(userIDs[:HRMMaxDimission+1:HRMMaxDimission+1], "hrm-left"))
+	assert.Nil(t, err)
+	assert.Len(t, dimissions, 1)
+	assert.Equal(t, "hrm-left", dimissions[0].UserID)
+	assert.Equal(t, 2, fake.Requests("/topapi/smartwork/hrm/employee/listdimission")-before)
+}
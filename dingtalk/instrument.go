@@ -0,0 +1,129 @@
+package dingtalk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// CallInfo 一次接口调用（含重试及access_token刷新）的观测数据
+	CallInfo struct {
+		Method         string
+		Path           string // 接口路径模板，新版服务端API路径中的unionId等参数被替换为占位符，如"/v1.0/todo/users/{unionId}/tasks"
+		Attempts       int    // 实际发送的请求数
+		TokenRefreshes int    // 因access_token过期而刷新的次数
+		StatusCode     int    // 最后一次请求的HTTP状态码
+		ErrorCode      string // 钉钉错误码，成功时为空
+		Err            error
+		Start          time.Time
+		Duration       time.Duration
+	}
+
+	// Instrumentation 接口调用的观测钩子，可用于链路追踪及指标采集
+	Instrumentation interface {
+		// StartCall 在每次接口调用开始时调用，返回的ctx将用于本次调用内的所有HTTP请求
+		StartCall(ctx context.Context) context.Context
+		// EndCall 在接口调用结束时调用，ctx为StartCall返回的ctx
+		EndCall(ctx context.Context, info *CallInfo)
+	}
+
+	callKey struct{}
+)
+
+// WithInstrumentation 为Client的所有接口调用启用观测
+func WithInstrumentation(instrumentation Instrumentation) ClientOption {
+	return func(o *clientOptions) {
+		o.instrumentation = instrumentation
+	}
+}
+
+// observe 将fn作为一次接口调用上报给Instrumentation，fn需使用传入的ctx发送请求
+func (ding *Client) observe(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ding.instrumentation == nil {
+		return fn(ctx)
+	}
+	info := &CallInfo{Start: time.Now()}
+	ctx = ding.instrumentation.StartCall(context.WithValue(ctx, callKey{}, info))
+	err := fn(ctx)
+	info.Duration = time.Since(info.Start)
+	info.Err = err
+	info.ErrorCode = ErrorCode(err)
+	ding.instrumentation.EndCall(ctx, info)
+	return err
+}
+
+// recordAttempt 在当前接口调用的观测数据中记录一次请求
+func recordAttempt(ctx context.Context, req *http.Request, res *http.Response) {
+	info, ok := ctx.Value(callKey{}).(*CallInfo)
+	if !ok {
+		return
+	}
+	if info.Path == "" {
+		info.Method = req.Method
+		info.Path = pathTemplate(req.URL)
+	}
+	info.Attempts++
+	if res != nil {
+		info.StatusCode = res.StatusCode
+	}
+}
+
+// pathParams 新版服务端API路径中紧随其后的一段为参数的路径段，及参数的占位符
+var pathParams = map[string]string{
+	"users":     "{unionId}",
+	"tasks":     "{taskId}",
+	"sources":   "{sourceId}",
+	"calendars": "{calendarId}",
+	"events":    "{eventId}",
+}
+
+// pathTemplate 观测使用的接口路径：将新版服务端API路径中的用户、待办、日程等参数替换为占位符，
+// 避免指标维度无限增长及用户标识泄露到观测数据中
+func pathTemplate(u *url.URL) string {
+	if !strings.HasPrefix(u.Path, "/v1.0/") && !strings.HasPrefix(u.Path, "/v2.0/") {
+		return u.Path
+	}
+	segments := strings.Split(u.Path, "/")
+	for i := 1; i < len(segments); i++ {
+		placeholder, ok := pathParams[segments[i-1]]
+		// 如".../org/tasks/query"、".../tasks/sources/{sourceId}"中tasks之后为操作或下一级资源而非参数
+		if _, nested := pathParams[segments[i]]; ok && !nested && segments[i] != "query" {
+			segments[i] = placeholder
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// recordTokenRefresh 在当前接口调用的观测数据中记录一次access_token刷新
+func recordTokenRefresh(ctx context.Context) {
+	if info, ok := ctx.Value(callKey{}).(*CallInfo); ok {
+		info.TokenRefreshes++
+	}
+}
+
+// ErrorCode 返回错误对应的钉钉错误码：服务端API为errcode（鉴权异常时为sub_code），新版服务端API为code，其余为HTTP状态码
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var de *DingtalkErr
+	var ge *GatewayErr
+	var he *HTTPError
+	switch {
+	case errors.As(err, &de):
+		if de.ErrorCode == AuthenticationAbnormal && de.SubCode != "" {
+			return de.SubCode
+		}
+		return strconv.Itoa(de.ErrorCode)
+	case errors.As(err, &ge):
+		return ge.Code
+	case errors.As(err, &he):
+		return strconv.Itoa(he.StatusCode)
+	}
+	return "error"
+}
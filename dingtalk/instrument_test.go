@@ -0,0 +1,80 @@
+package dingtalk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordInstrumentation struct {
+	mu    sync.Mutex
+	calls []*CallInfo
+}
+
+func (ri *recordInstrumentation) StartCall(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (ri *recordInstrumentation) EndCall(_ context.Context, info *CallInfo) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	ri.calls = append(ri.calls, info)
+}
+
+func TestClient_Instrumentation(t *testing.T) {
+	ri := new(recordInstrumentation)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gettoken" {
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","access_token":"fresh","expires_in":7200}`))
+			return
+		}
+		if r.URL.Query().Get("access_token") != "fresh" {
+			_, _ = w.Write([]byte(`{"errcode":40014,"errmsg":"不合法的access_token"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":60121,"errmsg":"找不到该用户"}`))
+	}))
+	defer srv.Close()
+
+	ding := NewClient(Option{AppKey: "key", AppSecret: "secret"}, WithBaseURL(srv.URL), WithInstrumentation(ri))
+	ding.SetAccessToken("stale")
+
+	_, _, err := ding.GetUserByUnionID(context.Background(), &RequestGetByUnionID{UnionID: "union"})
+	assert.True(t, IsUserNotExist(err))
+
+	if assert.Len(t, ri.calls, 2) {
+		token, call := ri.calls[0], ri.calls[1]
+		assert.Equal(t, "/gettoken", token.Path)
+		assert.Equal(t, 1, token.Attempts)
+		assert.Empty(t, token.ErrorCode)
+
+		assert.Equal(t, http.MethodPost, call.Method)
+		assert.Equal(t, "/topapi/user/getbyunionid", call.Path)
+		assert.Equal(t, 2, call.Attempts)
+		assert.Equal(t, 1, call.TokenRefreshes)
+		assert.Equal(t, http.StatusOK, call.StatusCode)
+		assert.Equal(t, "60121", call.ErrorCode)
+		assert.Equal(t, err, call.Err)
+	}
+}
+
+func TestPathTemplate(t *testing.T) {
+	cases := map[string]string{
+		"https://oapi.dingtalk.com/topapi/v2/user/get?access_token=x":                             "/topapi/v2/user/get",
+		"https://api.dingtalk.com/v1.0/todo/users/union/tasks":                                    "/v1.0/todo/users/{unionId}/tasks",
+		"https://api.dingtalk.com/v1.0/todo/users/union/tasks/task":                               "/v1.0/todo/users/{unionId}/tasks/{taskId}",
+		"https://api.dingtalk.com/v1.0/todo/users/union/tasks/sources/src":                        "/v1.0/todo/users/{unionId}/tasks/sources/{sourceId}",
+		"https://api.dingtalk.com/v1.0/todo/users/union/org/tasks/query":                          "/v1.0/todo/users/{unionId}/org/tasks/query",
+		"https://api.dingtalk.com/v1.0/calendar/users/union/calendars/primary/events/e/attendees": "/v1.0/calendar/users/{unionId}/calendars/{calendarId}/events/{eventId}/attendees",
+		"https://api.dingtalk.com/v1.0/calendar/users/union/meetingRooms/schedules/query":         "/v1.0/calendar/users/{unionId}/meetingRooms/schedules/query",
+	}
+	for raw, want := range cases {
+		u, _ := url.Parse(raw)
+		assert.Equal(t, want, pathTemplate(u), raw)
+	}
+}
@@ -0,0 +1,94 @@
+//go:build integration
+// +build integration
+
+package dingtalk
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 需要真实应用凭证的测试，dingtalktest未模拟的接口在此验证：
+// AgentID=xxx AppKey=xxx AppSecret=xxx UserID=xxx go test -tags integration ./dingtalk
+
+var integration struct {
+	client  *Client
+	userID  string
+	unionID string
+}
+
+func integrationClient(t *testing.T) *Client {
+	if integration.client != nil {
+		return integration.client
+	}
+	opt := Option{AgentID: os.Getenv("AgentID"), AppKey: os.Getenv("AppKey"), AppSecret: os.Getenv("AppSecret")}
+	if opt.IsEmpty() {
+		t.Skip("AppKey/AppSecret not provided")
+	}
+	client := NewClient(opt)
+	userID := os.Getenv("UserID")
+	user, _, err := client.GetUserInfoV2(context.Background(), &RequestUserGet{UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	integration.client, integration.userID, integration.unionID = client, userID, user.Result.UnionID
+	return client
+}
+
+func TestIntegration_ListAttendanceRecords(t *testing.T) {
+	client := integrationClient(t)
+	to := time.Now()
+	records, _, err := client.ListAttendanceRecords(context.Background(), []string{integration.userID}, to.AddDate(0, 0, -10), to)
+	assert.Nil(t, err)
+	for _, r := range records {
+		assert.Equal(t, integration.userID, r.UserID)
+	}
+}
+
+func TestIntegration_GetUserAttendanceGroup(t *testing.T) {
+	client := integrationClient(t)
+	_, _, err := client.GetUserAttendanceGroup(context.Background(), integration.userID)
+	assert.Nil(t, err)
+}
+
+func TestIntegration_CalendarEvent(t *testing.T) {
+	client := integrationClient(t)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	end := start.Add(time.Hour)
+	event, _, err := client.CreateCalendarEvent(context.Background(), integration.unionID, &CalendarEvent{
+		Summary: "go-clients",
+		Start:   &EventDateTime{DateTime: &start, TimeZone: "Asia/Shanghai"},
+		End:     &EventDateTime{DateTime: &end, TimeZone: "Asia/Shanghai"},
+		Recurrence: &Recurrence{
+			Pattern: &RecurrencePattern{Type: RecurrenceDaily, Interval: 1},
+			Range:   &RecurrenceRange{Type: RecurrenceNumbered, NumberOfOccurrences: 2},
+		},
+	})
+	assert.Nil(t, err)
+	_, err = client.DeleteCalendarEvent(context.Background(), integration.unionID, event.ID)
+	assert.Nil(t, err)
+}
+
+func TestIntegration_ListHRMEmployees(t *testing.T) {
+	client := integrationClient(t)
+	employees, _, err := client.ListHRMEmployees(context.Background(), []string{integration.userID}, []string{HRMFieldName, HRMFieldNowContractEndTime})
+	assert.Nil(t, err)
+	for _, emp := range employees {
+		_, ok := emp.Field(HRMFieldName)
+		assert.True(t, ok)
+	}
+}
+
+func TestIntegration_QueryDimissionEmployees(t *testing.T) {
+	client := integrationClient(t)
+	page, _, err := client.QueryDimissionEmployees(context.Background(), 0, 50)
+	assert.Nil(t, err)
+	if len(page.DataList) > 0 {
+		_, _, err = client.ListHRMDimission(context.Background(), page.DataList[:1])
+		assert.Nil(t, err)
+	}
+}
@@ -0,0 +1,117 @@
+package dingtalk
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jacexh/requests"
+)
+
+type (
+	// ResponseGetJSAPITicket https://oapi.dingtalk.com/get_jsapi_ticket
+	ResponseGetJSAPITicket struct {
+		*DingtalkErr `json:",inline"`
+		Ticket       string `json:"ticket"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+
+	// JSAPIConfig 前端dd.config所需的鉴权参数
+	JSAPIConfig struct {
+		AgentID   string `json:"agentId"`
+		CorpID    string `json:"corpId"`
+		TimeStamp int64  `json:"timeStamp"`
+		NonceStr  string `json:"nonceStr"`
+		Signature string `json:"signature"`
+	}
+)
+
+// jsapiTicketLeeway 提前刷新jsapi_ticket的时间，避免前端拿到即将过期的签名
+const jsapiTicketLeeway = 5 * time.Minute
+
+// GetJSAPITicket 获取jsapi_ticket，未过期时直接返回缓存 https://developers.dingtalk.com/document/app/obtain-jsapi_ticket
+func (ding *Client) GetJSAPITicket(ctx context.Context) (string, *http.Response, error) {
+	if ticket := ding.cachedJSAPITicket(); ticket != "" {
+		return ticket, nil, nil
+	}
+
+	var res *http.Response
+	v, err, _ := ding.flight.Do("jsapi_ticket", func() (interface{}, error) {
+		defer ding.flight.Forget("jsapi_ticket")
+		if ticket := ding.cachedJSAPITicket(); ticket != "" {
+			return ticket, nil
+		}
+
+		ret := new(ResponseGetJSAPITicket)
+		var err error
+		err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+			res, _, err = ding.get(
+				ctx,
+				ding.url+"/get_jsapi_ticket",
+				requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}},
+				UnmarshalAndParseError(ret),
+			)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		ding.setJSAPITicket(ret.Ticket, time.Duration(ret.ExpiresIn)*time.Second)
+		return ret.Ticket, nil
+	})
+	if err != nil {
+		return "", res, err
+	}
+	return v.(string), res, nil
+}
+
+// SignJSAPIConfig 使用jsapi_ticket为当前页面生成dd.config参数，url为调用dd.config的页面地址（不含#及其后部分）
+func (ding *Client) SignJSAPIConfig(ctx context.Context, pageURL, nonce string, timestamp int64) (*JSAPIConfig, *http.Response, error) {
+	ticket, res, err := ding.GetJSAPITicket(ctx)
+	if err != nil {
+		return nil, res, err
+	}
+	signature, err := SignJSAPITicket(ticket, nonce, timestamp, pageURL)
+	if err != nil {
+		return nil, res, err
+	}
+	opt := ding.Option()
+	return &JSAPIConfig{
+		AgentID:   opt.AgentID,
+		CorpID:    opt.CorpID,
+		TimeStamp: timestamp,
+		NonceStr:  nonce,
+		Signature: signature,
+	}, res, nil
+}
+
+// SignJSAPITicket 计算dd.config的签名 https://developers.dingtalk.com/document/app/jsapi-authentication
+func SignJSAPITicket(ticket, nonce string, timestamp int64, pageURL string) (string, error) {
+	decoded, err := url.QueryUnescape(pageURL)
+	if err != nil {
+		return "", err
+	}
+	plain := "jsapi_ticket=" + ticket + "&noncestr=" + nonce + "&timestamp=" + strconv.FormatInt(timestamp, 10) + "&url=" + decoded
+	sum := sha1.Sum([]byte(plain))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (ding *Client) cachedJSAPITicket() string {
+	ding.mu.RLock()
+	defer ding.mu.RUnlock()
+	if ding.ticket == "" || time.Now().After(ding.ticketExpiresAt) {
+		return ""
+	}
+	return ding.ticket
+}
+
+func (ding *Client) setJSAPITicket(ticket string, expiresIn time.Duration) {
+	ding.mu.Lock()
+	defer ding.mu.Unlock()
+	ding.ticket = ticket
+	ding.ticketExpiresAt = time.Now().Add(expiresIn - jsapiTicketLeeway)
+}
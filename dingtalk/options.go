@@ -4,6 +4,7 @@ type (
 	// Option 应用凭证
 	Option struct {
 		AgentID          string
+		CorpID           string
 		AppKey           string
 		AppSecret        string
 		LoginAppID       string
@@ -0,0 +1,112 @@
+package dingtalk
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+type (
+	// RateLimit 令牌桶参数，QPS<=0表示不限流
+	RateLimit struct {
+		QPS   float64
+		Burst int
+	}
+
+	// RateLimiter 客户端侧限流器，按应用区分令牌桶：每个应用一个总体令牌桶，每个应用的每个接口一个令牌桶。
+	// 同一个RateLimiter可由多个Client共享
+	RateLimiter struct {
+		mu        sync.Mutex
+		app       RateLimit
+		path      RateLimit
+		overrides map[string]RateLimit
+		buckets   map[string]*rate.Limiter
+	}
+)
+
+// 钉钉公布的调用频率限制 https://open.dingtalk.com/document/orgapp/invocation-frequency-limit
+var (
+	// DefaultPathRateLimit 单个应用调用单个接口的默认限流，与钉钉服务端API每秒20次的限制一致（超出时返回90018）
+	DefaultPathRateLimit = RateLimit{QPS: 20, Burst: 20}
+	// DefaultAppRateLimit 单个应用调用所有接口的总体限流，钉钉在应用维度的总体上限远高于单接口限制，默认不限流
+	DefaultAppRateLimit = RateLimit{}
+	// DefaultPathRateLimits 默认的接口限流，未列出的接口使用DefaultPathRateLimit。
+	// 获取凭证的接口结果会被缓存、调用频率很低，且凭证过期时所有请求都在等待刷新，不做客户端限流
+	DefaultPathRateLimits = map[string]RateLimit{
+		"/gettoken":                {},
+		"/get_jsapi_ticket":        {},
+		"/service/get_suite_token": {},
+		"/service/get_corp_token":  {},
+	}
+)
+
+// NewRateLimiter overrides以接口路径为key覆盖path限流，新版服务端API的路径以产品为粒度，如"/v1.0/todo"
+func NewRateLimiter(app, path RateLimit, overrides map[string]RateLimit) *RateLimiter {
+	o := make(map[string]RateLimit, len(overrides))
+	for k, v := range overrides {
+		o[k] = v
+	}
+	return &RateLimiter{
+		app:       app,
+		path:      path,
+		overrides: o,
+		buckets:   make(map[string]*rate.Limiter),
+	}
+}
+
+// NewDefaultRateLimiter 使用DefaultAppRateLimit、DefaultPathRateLimit及DefaultPathRateLimits的限流器
+func NewDefaultRateLimiter() *RateLimiter {
+	return NewRateLimiter(DefaultAppRateLimit, DefaultPathRateLimit, DefaultPathRateLimits)
+}
+
+// Wait 等待app调用path的令牌，ctx取消时立即返回
+func (rl *RateLimiter) Wait(ctx context.Context, app, path string) error {
+	if rl == nil {
+		return nil
+	}
+	limit, ok := rl.overrides[path]
+	if !ok {
+		limit = rl.path
+	}
+	if l := rl.bucket(app+" "+path, limit); l != nil {
+		if err := l.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if l := rl.bucket(app, rl.app); l != nil {
+		return l.Wait(ctx)
+	}
+	return nil
+}
+
+func (rl *RateLimiter) bucket(key string, limit RateLimit) *rate.Limiter {
+	if limit.QPS <= 0 {
+		return nil
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	l, ok := rl.buckets[key]
+	if !ok {
+		burst := limit.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		l = rate.NewLimiter(rate.Limit(limit.QPS), burst)
+		rl.buckets[key] = l
+	}
+	return l
+}
+
+// rateLimitPath 限流使用的接口路径：服务端API取完整路径，新版服务端API的路径中含有unionId等参数，取前两段（如"/v1.0/todo"）
+func rateLimitPath(u *url.URL) string {
+	if strings.HasPrefix(u.Path, "/v1.0/") || strings.HasPrefix(u.Path, "/v2.0/") {
+		segments := strings.SplitN(u.Path, "/", 4)
+		if len(segments) >= 3 {
+			return "/" + segments[1] + "/" + segments[2]
+		}
+	}
+	return u.Path
+}
@@ -0,0 +1,52 @@
+package dingtalk
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	rl := NewRateLimiter(RateLimit{}, RateLimit{QPS: 1, Burst: 1}, map[string]RateLimit{"/gettoken": {}})
+
+	assert.Nil(t, rl.Wait(context.Background(), "app", "/topapi/v2/user/get"))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, rl.Wait(ctx, "app", "/topapi/v2/user/get"))
+
+	// 不同应用、不同接口互不影响，被覆盖为0的接口不限流
+	assert.Nil(t, rl.Wait(ctx, "other", "/topapi/v2/user/get"))
+	assert.Nil(t, rl.Wait(ctx, "app", "/topapi/v2/department/get"))
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, rl.Wait(ctx, "app", "/gettoken"))
+	}
+
+	var nilLimiter *RateLimiter
+	assert.Nil(t, nilLimiter.Wait(ctx, "app", "/gettoken"))
+}
+
+func TestRateLimitPath(t *testing.T) {
+	u, _ := url.Parse("https://api.dingtalk.com/v1.0/todo/users/union/tasks/task")
+	assert.Equal(t, "/v1.0/todo", rateLimitPath(u))
+	u, _ = url.Parse("https://oapi.dingtalk.com/topapi/v2/user/get?access_token=x")
+	assert.Equal(t, "/topapi/v2/user/get", rateLimitPath(u))
+}
+
+func TestNewDefaultRateLimiter(t *testing.T) {
+	rl := NewDefaultRateLimiter()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// 单接口按每秒20次限流，应用维度及获取凭证的接口不限流
+	for i := 0; i < DefaultPathRateLimit.Burst; i++ {
+		assert.Nil(t, rl.Wait(ctx, "app", "/topapi/v2/user/get"))
+		assert.Nil(t, rl.Wait(ctx, "app", "/topapi/v2/department/get"))
+	}
+	assert.NotNil(t, rl.Wait(ctx, "app", "/topapi/v2/user/get"))
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, rl.Wait(ctx, "app", "/gettoken"))
+	}
+}
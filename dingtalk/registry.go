@@ -0,0 +1,138 @@
+package dingtalk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type (
+	// OptionSource 按AppKey提供应用凭证，如配置文件、配置中心
+	OptionSource interface {
+		Lookup(ctx context.Context, appKey string) (Option, error)
+	}
+
+	// OptionSourceFunc 函数形式的OptionSource
+	OptionSourceFunc func(ctx context.Context, appKey string) (Option, error)
+
+	// StaticOptionSource 以AppKey为键的静态凭证表
+	StaticOptionSource map[string]Option
+
+	// Registry 多应用客户端注册表，按AppKey懒加载并缓存Client，所有Client共享同一个HTTP会话及限流器
+	Registry struct {
+		mu      sync.RWMutex
+		source  OptionSource
+		proto   *Client // 不含应用凭证，新建的Client与其共享HTTP会话
+		clients map[string]*Client
+		flight  singleflight.Group
+	}
+)
+
+func (fn OptionSourceFunc) Lookup(ctx context.Context, appKey string) (Option, error) {
+	return fn(ctx, appKey)
+}
+
+func (ss StaticOptionSource) Lookup(_ context.Context, appKey string) (Option, error) {
+	opt, ok := ss[appKey]
+	if !ok {
+		return Option{}, fmt.Errorf("app %s not found", appKey)
+	}
+	return opt, nil
+}
+
+// NewRegistry opts作用于所有由Registry创建的Client
+func NewRegistry(source OptionSource, opts ...ClientOption) *Registry {
+	return &Registry{
+		source:  source,
+		proto:   NewClient(Option{}, opts...),
+		clients: make(map[string]*Client),
+	}
+}
+
+// Get 返回appKey对应的Client，首次调用时从OptionSource加载凭证
+func (reg *Registry) Get(ctx context.Context, appKey string) (*Client, error) {
+	reg.mu.RLock()
+	ding, ok := reg.clients[appKey]
+	reg.mu.RUnlock()
+	if ok {
+		return ding, nil
+	}
+
+	v, err, _ := reg.flight.Do(appKey, func() (interface{}, error) {
+		defer reg.flight.Forget(appKey)
+		reg.mu.RLock()
+		ding, ok := reg.clients[appKey]
+		reg.mu.RUnlock()
+		if ok {
+			return ding, nil
+		}
+
+		opt, err := reg.source.Lookup(ctx, appKey)
+		if err != nil {
+			return nil, err
+		}
+		if opt.IsEmpty() {
+			return nil, fmt.Errorf("app %s has no credential", appKey)
+		}
+		ding = reg.proto.derive(opt)
+		reg.mu.Lock()
+		reg.clients[appKey] = ding
+		reg.mu.Unlock()
+		return ding, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Client), nil
+}
+
+// Reload 重新从OptionSource加载appKey的凭证并原地更新已创建的Client，尚未创建的Client不受影响；
+// 加载到空凭证时返回错误并保留原凭证
+func (reg *Registry) Reload(ctx context.Context, appKey string) error {
+	reg.mu.RLock()
+	ding, ok := reg.clients[appKey]
+	reg.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	opt, err := reg.source.Lookup(ctx, appKey)
+	if err != nil {
+		return err
+	}
+	if opt.IsEmpty() {
+		return fmt.Errorf("app %s has no credential", appKey)
+	}
+	ding.WithAppOption(opt)
+	return nil
+}
+
+// ReloadAll 重新加载所有已创建Client的凭证
+func (reg *Registry) ReloadAll(ctx context.Context) error {
+	for _, appKey := range reg.AppKeys() {
+		if err := reg.Reload(ctx, appKey); err != nil {
+			return fmt.Errorf("reload app %s: %w", appKey, err)
+		}
+	}
+	return nil
+}
+
+// Remove 移除appKey对应的Client，下次Get时重新创建
+func (reg *Registry) Remove(appKey string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	delete(reg.clients, appKey)
+}
+
+// AppKeys 返回已创建Client的AppKey
+func (reg *Registry) AppKeys() []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	keys := make([]string, 0, len(reg.clients))
+	for key := range reg.clients {
+		keys = append(keys, key)
+	}
+	return keys
+}
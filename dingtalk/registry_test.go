@@ -0,0 +1,56 @@
+package dingtalk
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Get(t *testing.T) {
+	source := StaticOptionSource{"app": {AppKey: "app", AppSecret: "secret"}}
+	reg := NewRegistry(source)
+
+	var wg sync.WaitGroup
+	clients := make([]*Client, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i], _ = reg.Get(context.Background(), "app")
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range clients {
+		assert.Same(t, clients[0], c)
+	}
+	assert.Same(t, reg.proto.httpClient, clients[0].httpClient)
+
+	_, err := reg.Get(context.Background(), "missing")
+	assert.NotNil(t, err)
+}
+
+func TestRegistry_Reload(t *testing.T) {
+	source := StaticOptionSource{"app": {AppKey: "app", AppSecret: "secret"}}
+	reg := NewRegistry(source)
+	ding, err := reg.Get(context.Background(), "app")
+	assert.Nil(t, err)
+	ding.SetAccessToken("token")
+
+	source["app"] = Option{AppKey: "app", AppSecret: "rotated"}
+	assert.Nil(t, reg.ReloadAll(context.Background()))
+	assert.Equal(t, "rotated", ding.Option().AppSecret)
+	assert.Equal(t, "token", ding.AccessToken())
+}
+
+func TestRegistry_ReloadEmpty(t *testing.T) {
+	source := StaticOptionSource{"app": {AppKey: "app", AppSecret: "secret"}}
+	reg := NewRegistry(source)
+	ding, err := reg.Get(context.Background(), "app")
+	assert.Nil(t, err)
+
+	source["app"] = Option{}
+	assert.NotNil(t, reg.Reload(context.Background(), "app"))
+	assert.Equal(t, "secret", ding.Option().AppSecret)
+}
@@ -0,0 +1,224 @@
+package dingtalk
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jacexh/requests"
+)
+
+type (
+	// ReportContent 日志中的一项填写内容
+	ReportContent struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+		Sort  string `json:"sort"`
+		Type  string `json:"type"`
+	}
+
+	// Report 日志
+	Report struct {
+		ReportID     string           `json:"report_id"`
+		TemplateName string           `json:"template_name"`
+		CreatorID    string           `json:"creator_id"`
+		CreatorName  string           `json:"creator_name"`
+		DeptName     string           `json:"dept_name"`
+		Remark       string           `json:"remark"`
+		Contents     []*ReportContent `json:"contents"`
+		CreateTime   UnixTimestamp    `json:"create_time"`
+		ModifiedTime UnixTimestamp    `json:"modified_time"`
+		Images       []string         `json:"images,omitempty"`
+	}
+
+	// RequestListReports https://oapi.dingtalk.com/topapi/report/list ，时间跨度不超过180天
+	RequestListReports struct {
+		StartTime    time.Time
+		EndTime      time.Time
+		TemplateName string
+		UserID       string
+		Cursor       int
+		Size         int // 最大20
+	}
+
+	ReportPage struct {
+		DataList   []*Report `json:"data_list"`
+		Size       int       `json:"size"`
+		NextCursor int       `json:"next_cursor"`
+		HasMore    bool      `json:"has_more"`
+	}
+
+	ResponseListReports struct {
+		BasicResponse `json:",inline"`
+		Result        *ReportPage `json:"result"`
+	}
+
+	ReportTemplateField struct {
+		FieldName string `json:"field_name"`
+		Sort      int    `json:"sort"`
+		Type      int    `json:"type"`
+	}
+
+	ReportReceiver struct {
+		UserID   string `json:"userid"`
+		UserName string `json:"user_name"`
+	}
+
+	ReportConversation struct {
+		ConversationID string `json:"conversation_id"`
+		Title          string `json:"title"`
+	}
+
+	// ReportTemplate 日志模板
+	ReportTemplate struct {
+		ID                   string                 `json:"id"`
+		Name                 string                 `json:"name"`
+		UserID               string                 `json:"userid"`
+		UserName             string                 `json:"user_name"`
+		Fields               []*ReportTemplateField `json:"fields"`
+		DefaultReceivers     []*ReportReceiver      `json:"default_receivers"`
+		DefaultReceivedConvs []*ReportConversation  `json:"default_received_convs"`
+	}
+
+	ResponseGetReportTemplate struct {
+		BasicResponse `json:",inline"`
+		Result        *ReportTemplate `json:"result"`
+	}
+
+	// ReportComment 日志评论
+	ReportComment struct {
+		UserID     string   `json:"userid"`
+		Content    string   `json:"content"`
+		CreateTime DingTime `json:"create_time"`
+	}
+
+	ReportCommentPage struct {
+		Comments   []*ReportComment `json:"comments"`
+		HasMore    bool             `json:"has_more"`
+		NextCursor int              `json:"next_cursor"`
+	}
+
+	ResponseGetReportComments struct {
+		BasicResponse `json:",inline"`
+		Result        *ReportCommentPage `json:"result"`
+	}
+
+	// ReportStatistics 日志的已读、评论、点赞数
+	ReportStatistics struct {
+		ReadNum        int `json:"read_num"`
+		CommentNum     int `json:"comment_num"`
+		CommentUserNum int `json:"comment_user_num"`
+		LikeNum        int `json:"like_num"`
+	}
+
+	ResponseGetReportStatistics struct {
+		BasicResponse `json:",inline"`
+		Result        *ReportStatistics `json:"result"`
+	}
+)
+
+// Value 返回key对应的填写内容
+func (r *Report) Value(key string) (string, bool) {
+	for _, c := range r.Contents {
+		if c.Key == key {
+			return c.Value, true
+		}
+	}
+	return "", false
+}
+
+// Sections 以key-value形式返回日志内容
+func (r *Report) Sections() map[string]string {
+	sections := make(map[string]string, len(r.Contents))
+	for _, c := range r.Contents {
+		sections[c.Key] = c.Value
+	}
+	return sections
+}
+
+// ListReports 获取用户发出的日志列表，通过NextCursor翻页 https://developers.dingtalk.com/document/app/query-logs
+func (ding *Client) ListReports(ctx context.Context, req *RequestListReports) (*ReportPage, *http.Response, error) {
+	ret := new(ResponseListReports)
+	var res *http.Response
+	var err error
+
+	body := map[string]interface{}{
+		"start_time": req.StartTime.UnixNano() / 1e6,
+		"end_time":   req.EndTime.UnixNano() / 1e6,
+		"cursor":     req.Cursor,
+		"size":       req.Size,
+	}
+	if req.TemplateName != "" {
+		body["template_name"] = req.TemplateName
+	}
+	if req.UserID != "" {
+		body["userid"] = req.UserID
+	}
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.url+"/topapi/report/list",
+			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: body},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return ret.Result, res, err
+}
+
+// GetReportTemplate 获取日志模板详情 https://developers.dingtalk.com/document/app/obtains-the-details-of-a-log-template
+func (ding *Client) GetReportTemplate(ctx context.Context, templateName, userID string) (*ReportTemplate, *http.Response, error) {
+	ret := new(ResponseGetReportTemplate)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.url+"/topapi/report/template/getbyname",
+			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: requests.Any{"template_name": templateName, "userid": userID}},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return ret.Result, res, err
+}
+
+// GetReportComments 获取日志评论详情 https://developers.dingtalk.com/document/app/queries-log-comment-details
+func (ding *Client) GetReportComments(ctx context.Context, reportID string, offset, size int) (*ReportCommentPage, *http.Response, error) {
+	ret := new(ResponseGetReportComments)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.url+"/topapi/report/comment/list",
+			requests.Params{
+				Query: requests.Any{"access_token": ding.AccessToken()},
+				Json:  map[string]interface{}{"report_id": reportID, "offset": offset, "size": size},
+			},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return ret.Result, res, err
+}
+
+// GetReportStatistics 获取日志统计数据 https://developers.dingtalk.com/document/app/queries-log-statistics
+func (ding *Client) GetReportStatistics(ctx context.Context, reportID string) (*ReportStatistics, *http.Response, error) {
+	ret := new(ResponseGetReportStatistics)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.url+"/topapi/report/statistics",
+			requests.Params{Query: requests.Any{"access_token": ding.AccessToken()}, Json: requests.Any{"report_id": reportID}},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return ret.Result, res, err
+}
@@ -0,0 +1,137 @@
+package dingtalk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ListReports(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []map[string]interface{}
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topapi/report/list", r.URL.Path)
+		assert.Equal(t, "token", r.URL.Query().Get("access_token"))
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+
+		// 共5条日志，每页2条
+		cursor := int(body["cursor"].(float64))
+		var list []string
+		for i := cursor; i < cursor+2 && i < 5; i++ {
+			list = append(list, fmt.Sprintf(`{"report_id":"r%d","template_name":"日报","creator_id":"u","create_time":1622505600000,"contents":[{"key":"今日完成","value":"v%d","sort":"0","type":"1"}]}`, i, i))
+		}
+		hasMore := cursor+2 < 5
+		_, _ = fmt.Fprintf(w, `{"errcode":0,"errmsg":"ok","result":{"data_list":[%s],"size":2,"has_more":%t,"next_cursor":%d}}`, strings.Join(list, ","), hasMore, cursor+2)
+	})
+	defer closeFn()
+	ding.SetAccessToken("token")
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	req := &RequestListReports{StartTime: start, EndTime: start.AddDate(0, 0, 7), TemplateName: "日报", Size: 2}
+	var reports []*Report
+	for {
+		page, _, err := ding.ListReports(context.Background(), req)
+		assert.Nil(t, err)
+		reports = append(reports, page.DataList...)
+		if !page.HasMore {
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+	assert.Len(t, reports, 5)
+	assert.Len(t, bodies, 3)
+	assert.EqualValues(t, 4, bodies[2]["cursor"])
+	assert.EqualValues(t, start.UnixNano()/1e6, bodies[0]["start_time"])
+	assert.Equal(t, "日报", bodies[0]["template_name"])
+	assert.NotContains(t, bodies[0], "userid")
+
+	value, ok := reports[4].Value("今日完成")
+	assert.True(t, ok)
+	assert.Equal(t, "v4", value)
+	assert.Equal(t, map[string]string{"今日完成": "v4"}, reports[4].Sections())
+	assert.Equal(t, int64(1622505600), reports[0].CreateTime.Time().Unix())
+}
+
+func TestClient_ReportDetails(t *testing.T) {
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/topapi/report/template/getbyname":
+			assert.Equal(t, map[string]interface{}{"template_name": "周报", "userid": "u"}, body)
+			_, _ = w.Write([]byte(`{"errcode":0,"result":{"id":"tpl","name":"周报","fields":[{"field_name":"本周完成","sort":0,"type":1}],"default_receivers":[{"userid":"boss","user_name":"老板"}]}}`))
+		case "/topapi/report/statistics":
+			assert.Equal(t, "r1", body["report_id"])
+			_, _ = w.Write([]byte(`{"errcode":0,"result":{"read_num":3,"comment_num":2,"comment_user_num":1,"like_num":4}}`))
+		case "/topapi/report/comment/list":
+			assert.EqualValues(t, 10, body["offset"])
+			_, _ = w.Write([]byte(`{"errcode":0,"result":{"comments":[{"userid":"boss","content":"好","create_time":"2021-06-01 18:00:00"}],"has_more":false,"next_cursor":11}}`))
+		}
+	})
+	defer closeFn()
+	ding.SetAccessToken("token")
+	ctx := context.Background()
+
+	tpl, _, err := ding.GetReportTemplate(ctx, "周报", "u")
+	assert.Nil(t, err)
+	assert.Equal(t, "tpl", tpl.ID)
+	assert.Equal(t, "本周完成", tpl.Fields[0].FieldName)
+	assert.Equal(t, "boss", tpl.DefaultReceivers[0].UserID)
+
+	stat, _, err := ding.GetReportStatistics(ctx, "r1")
+	assert.Nil(t, err)
+	assert.Equal(t, &ReportStatistics{ReadNum: 3, CommentNum: 2, CommentUserNum: 1, LikeNum: 4}, stat)
+
+	comments, _, err := ding.GetReportComments(ctx, "r1", 10, 20)
+	assert.Nil(t, err)
+	assert.False(t, comments.HasMore)
+	assert.Equal(t, time.Date(2021, 6, 1, 18, 0, 0, 0, time.UTC), comments.Comments[0].CreateTime.Time)
+}
+
+func TestClient_ListReports_Error(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/topapi/report/list":
+			if n == 1 {
+				// 系统繁忙时重试
+				_, _ = w.Write([]byte(`{"errcode":-1,"errmsg":"系统繁忙"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"errcode":400002,"errmsg":"时间跨度不能超过180天","request_id":"req-1"}`))
+		case "/topapi/report/template/getbyname":
+			_, _ = w.Write([]byte(`{"errcode":60121,"errmsg":"找不到该用户"}`))
+		}
+	})
+	defer closeFn()
+	ding.SetAccessToken("token")
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, _, err := ding.ListReports(context.Background(), &RequestListReports{StartTime: start, EndTime: start.AddDate(1, 0, 0), Size: 20})
+	assert.True(t, IsInvalidParam(err))
+	var de *DingtalkErr
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "/topapi/report/list", de.Path)
+	assert.Equal(t, "req-1", de.RequestID)
+	assert.Equal(t, 2, calls)
+
+	_, _, err = ding.GetReportTemplate(context.Background(), "周报", "missing")
+	assert.True(t, IsUserNotExist(err))
+}
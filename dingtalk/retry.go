@@ -0,0 +1,148 @@
+package dingtalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy 重试策略：网络错误、5xx及钉钉限流错误按指数退避重试，access_token过期时刷新后立即重试
+type RetryPolicy struct {
+	MaxAttempts     int           // 含首次请求在内的最大尝试次数，<=1表示不重试
+	InitialInterval time.Duration // 首次重试前的等待时间
+	MaxInterval     time.Duration // 单次等待时间上限
+	Multiplier      float64       // 每次重试等待时间的增长倍数
+	Jitter          float64       // 随机抖动比例，取值0~1
+	MaxElapsedTime  time.Duration // 自首次请求起允许重试的最长时间，0表示不限制
+}
+
+const (
+	idempotent    = true
+	nonIdempotent = false
+)
+
+var (
+	// DefaultRetryPolicy 默认重试策略
+	DefaultRetryPolicy = RetryPolicy{
+		MaxAttempts:     3,
+		InitialInterval: 200 * time.Millisecond,
+		MaxInterval:     5 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxElapsedTime:  30 * time.Second,
+	}
+
+	// NoRetryPolicy 仅在access_token过期时重试
+	NoRetryPolicy = RetryPolicy{MaxAttempts: 1}
+)
+
+// Backoff 第attempt次重试（从1开始）前的等待时间
+func (rp RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := rp.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(rp.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if rp.MaxInterval > 0 && d > float64(rp.MaxInterval) {
+		d = float64(rp.MaxInterval)
+	}
+	if rp.Jitter > 0 {
+		d += d * rp.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// isTransient 请求可能已被服务端处理的临时性错误，仅对幂等请求重试
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.StatusCode >= 500
+	}
+	var ge *GatewayErr
+	if errors.As(err, &ge) {
+		return ge.StatusCode >= 500
+	}
+	var de *DingtalkErr
+	if errors.As(err, &de) {
+		return de.ErrorCode == SystemBusy
+	}
+	// 仅重试网络错误，报文解析失败、缺少凭证等错误重试也无法恢复
+	var ue *url.Error
+	var ne net.Error
+	return errors.As(err, &ue) || errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withRetry 按RetryPolicy执行fn。限流及access_token过期时请求未被处理，任何请求都可以重试；
+// 网络错误、5xx等无法确认是否已处理的错误，仅当请求幂等时重试
+func (ding *Client) withRetry(ctx context.Context, isIdempotent bool, fn func(ctx context.Context) error) error {
+	return ding.observe(ctx, func(ctx context.Context) error {
+		return ding.retryLoop(ctx, isIdempotent, fn)
+	})
+}
+
+func (ding *Client) retryLoop(ctx context.Context, isIdempotent bool, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	tokenRefreshed := false
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if isAccessTokenExpired(err) {
+			if tokenRefreshed {
+				return err
+			}
+			tokenRefreshed = true
+			recordTokenRefresh(ctx)
+			if akErr := ding.refreshAccessToken(ctx); akErr != nil {
+				return fmt.Errorf("%w | %s", err, akErr.Error())
+			}
+			attempt--
+			continue
+		}
+
+		if !IsRateLimited(err) && !(isIdempotent && isTransient(err)) {
+			return err
+		}
+		if attempt >= ding.retry.MaxAttempts {
+			return err
+		}
+		wait := ding.retry.Backoff(attempt)
+		if ding.retry.MaxElapsedTime > 0 && time.Since(start)+wait > ding.retry.MaxElapsedTime {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w | %s", ctx.Err(), err.Error())
+		case <-timer.C:
+		}
+	}
+}
+
+// refreshAccessToken 并发调用时只会请求一次gettoken
+func (ding *Client) refreshAccessToken(ctx context.Context) error {
+	expired := ding.AccessToken()
+	_, err, _ := ding.flight.Do("access_token", func() (interface{}, error) {
+		if ding.suite != nil {
+			ding.suite.forgetCorpToken(ding.Option().CorpID, expired)
+		}
+		ak, _, reqErr := ding.GetAccessToken(ctx)
+		ding.flight.Forget("access_token")
+		return ak, reqErr
+	})
+	return err
+}
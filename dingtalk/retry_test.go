@@ -0,0 +1,175 @@
+package dingtalk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond, Multiplier: 2}
+
+func newRetryTestClient(handler http.HandlerFunc) (*Client, func()) {
+	srv := httptest.NewServer(handler)
+	ding := NewClient(Option{AppKey: "key", AppSecret: "secret"}, WithBaseURL(srv.URL), WithRetryPolicy(fastRetry), WithRateLimiter(nil))
+	return ding, srv.Close
+}
+
+func TestClient_withRetry_ServerError(t *testing.T) {
+	var calls int32
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","count":10,"process_instance_id":"pi"}`))
+	})
+	defer closeFn()
+
+	count, _, err := ding.GetOrganizationUserCount(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, count)
+	assert.EqualValues(t, 2, calls)
+
+	// 非幂等请求遇到5xx不重试
+	atomic.StoreInt32(&calls, 0)
+	_, _, err = ding.CreateProcessInstance(context.Background(), &RequestCreateProcessInstance{})
+	var he *HTTPError
+	assert.True(t, errors.As(err, &he))
+	assert.Equal(t, http.StatusBadGateway, he.StatusCode)
+	assert.EqualValues(t, 1, calls)
+}
+
+func TestClient_withRetry_ServerErrorWithErrcode(t *testing.T) {
+	var calls int32
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		switch {
+		case n == 1:
+			// 响应体可以正常解析且errcode为0，仍按HTTP状态码处理
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		case n == 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errcode":-1,"errmsg":"系统繁忙"}`))
+		case r.URL.Path == "/topapi/v2/department/get":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		default:
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","count":10}`))
+		}
+	})
+	defer closeFn()
+	ding.SetAccessToken("token")
+
+	count, _, err := ding.GetOrganizationUserCount(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, count)
+	assert.EqualValues(t, 3, calls)
+
+	// 4xx不重试
+	_, _, err = ding.GetDepartmentV2(context.Background(), 404)
+	var he *HTTPError
+	assert.True(t, errors.As(err, &he))
+	assert.Equal(t, http.StatusNotFound, he.StatusCode)
+	assert.True(t, IsNotFound(err))
+	assert.EqualValues(t, 4, calls)
+}
+
+func TestClient_withRetry_Throttled(t *testing.T) {
+	var calls int32
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			_, _ = w.Write([]byte(`{"errcode":88,"errmsg":"ding talk error[subcode=90018]","sub_code":"90018","sub_msg":"too many requests"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","process_instance_id":"pi"}`))
+	})
+	defer closeFn()
+
+	id, _, err := ding.CreateProcessInstance(context.Background(), &RequestCreateProcessInstance{})
+	assert.Nil(t, err)
+	assert.Equal(t, "pi", id)
+	assert.EqualValues(t, 3, calls)
+}
+
+func TestClient_withRetry_AccessTokenExpired(t *testing.T) {
+	var tokens, calls int32
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gettoken" {
+			atomic.AddInt32(&tokens, 1)
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","access_token":"fresh","expires_in":7200}`))
+			return
+		}
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Query().Get("access_token") != "fresh" {
+			_, _ = w.Write([]byte(`{"errcode":40014,"errmsg":"不合法的access_token"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","process_instance_id":"pi"}`))
+	})
+	defer closeFn()
+
+	id, _, err := ding.CreateProcessInstance(context.Background(), &RequestCreateProcessInstance{})
+	assert.Nil(t, err)
+	assert.Equal(t, "pi", id)
+	assert.EqualValues(t, 1, tokens)
+	assert.EqualValues(t, 2, calls)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	assert.Equal(t, 200*time.Millisecond, RetryPolicy{InitialInterval: 200 * time.Millisecond, Multiplier: 2}.Backoff(1))
+	assert.Equal(t, 800*time.Millisecond, RetryPolicy{InitialInterval: 200 * time.Millisecond, Multiplier: 2}.Backoff(3))
+	assert.Equal(t, time.Second, RetryPolicy{InitialInterval: 200 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}.Backoff(10))
+}
+
+func TestClient_withRetry_NotTransient(t *testing.T) {
+	var calls int32
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = w.Write([]byte(`not json`))
+	})
+	defer closeFn()
+
+	_, _, err := ding.GetOrganizationUserCount(context.Background(), 1)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 1, calls)
+}
+
+func TestClient_withRetry_NetworkError(t *testing.T) {
+	var calls int32
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// 直接断开连接
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","count":10}`))
+	})
+	defer closeFn()
+
+	count, _, err := ding.GetOrganizationUserCount(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, count)
+	assert.EqualValues(t, 2, calls)
+}
+
+func TestClient_withRetry_Canceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ding, closeFn := newRetryTestClient(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errcode":88,"errmsg":"ding talk error[subcode=90018]","sub_code":"90018","sub_msg":"too many requests"}`))
+	})
+	defer closeFn()
+	ding.retry.InitialInterval, ding.retry.MaxInterval = time.Second, time.Second
+
+	// 退避等待期间ctx超时
+	_, _, err := ding.GetOrganizationUserCount(ctx, 1)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -0,0 +1,407 @@
+package dingtalk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jacexh/requests"
+	"golang.org/x/sync/singleflight"
+)
+
+type (
+	// SuiteOption 第三方企业应用凭证
+	SuiteOption struct {
+		SuiteID     string
+		SuiteKey    string
+		SuiteSecret string
+		Token       string // 回调签名token
+		AESKey      string // 回调数据加密密钥
+	}
+
+	// SuiteTicketStore 持久化钉钉推送的suite_ticket，多实例部署时应使用共享存储
+	SuiteTicketStore interface {
+		GetSuiteTicket(ctx context.Context, suiteKey string) (string, error)
+		SetSuiteTicket(ctx context.Context, suiteKey, ticket string) error
+	}
+
+	// MemorySuiteTicketStore 基于内存的SuiteTicketStore，仅适用于单实例
+	MemorySuiteTicketStore struct {
+		mu      sync.RWMutex
+		tickets map[string]string
+	}
+
+	// SuiteClient 第三方企业应用（ISV）客户端
+	SuiteClient struct {
+		mu     sync.RWMutex
+		ding   *Client // 不含应用凭证，仅用于发送请求
+		opt    SuiteOption
+		store  SuiteTicketStore
+		crypto *CallbackCrypto
+		flight singleflight.Group
+
+		token          string
+		tokenExpiresAt time.Time
+		corps          map[string]*Client
+		corpTokens     map[string]corpToken
+	}
+
+	// corpToken 授权企业的access_token缓存
+	corpToken struct {
+		token     string
+		expiresAt time.Time
+	}
+
+	// SuiteCallbackEvent 第三方企业应用回调事件 https://developers.dingtalk.com/document/app/push-events
+	SuiteCallbackEvent struct {
+		EventType   string `json:"EventType"`
+		SuiteKey    string `json:"SuiteKey,omitempty"`
+		SuiteTicket string `json:"SuiteTicket,omitempty"`
+		AuthCode    string `json:"AuthCode,omitempty"`
+		AuthCorpID  string `json:"AuthCorpId,omitempty"`
+		TimeStamp   string `json:"TimeStamp,omitempty"`
+		// PermanentCode tmp_auth_code事件激活成功后获得的永久授权码
+		PermanentCode *PermanentCode `json:"-"`
+	}
+
+	// ResponseGetSuiteAccessToken https://oapi.dingtalk.com/service/get_suite_token
+	ResponseGetSuiteAccessToken struct {
+		*DingtalkErr     `json:",inline"`
+		SuiteAccessToken string `json:"suite_access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+	}
+
+	AuthCorpInfo struct {
+		CorpID   string `json:"corpid"`
+		CorpName string `json:"corp_name"`
+	}
+
+	// PermanentCode 企业的永久授权码
+	PermanentCode struct {
+		PermanentCode string        `json:"permanent_code"`
+		AuthCorpInfo  *AuthCorpInfo `json:"auth_corp_info"`
+	}
+
+	// ResponseGetPermanentCode https://oapi.dingtalk.com/service/get_permanent_code
+	ResponseGetPermanentCode struct {
+		*DingtalkErr   `json:",inline"`
+		*PermanentCode `json:",inline"`
+	}
+)
+
+const (
+	SuiteEventSuiteTicket  = "suite_ticket"
+	SuiteEventTmpAuthCode  = "tmp_auth_code"
+	SuiteEventCheckURL     = "check_url"
+	SuiteEventCheckCreate  = "check_create_suite_url"
+	SuiteEventCheckUpdate  = "check_update_suite_url"
+	SuiteEventRelieveAuth  = "suite_relieve"
+	SuiteEventChangeAuth   = "change_auth"
+	suiteAccessTokenLeeway = 5 * time.Minute // suite_access_token及授权企业access_token提前过期的时间
+)
+
+// ErrNoSuiteTicket 尚未收到钉钉推送的suite_ticket
+var ErrNoSuiteTicket = errors.New("suite_ticket not received yet")
+
+func NewMemorySuiteTicketStore() *MemorySuiteTicketStore {
+	return &MemorySuiteTicketStore{tickets: make(map[string]string)}
+}
+
+func (ms *MemorySuiteTicketStore) GetSuiteTicket(_ context.Context, suiteKey string) (string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.tickets[suiteKey], nil
+}
+
+func (ms *MemorySuiteTicketStore) SetSuiteTicket(_ context.Context, suiteKey, ticket string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.tickets[suiteKey] = ticket
+	return nil
+}
+
+// NewSuiteClient store为nil时使用MemorySuiteTicketStore，opts同时作用于各授权企业的Client
+func NewSuiteClient(opt SuiteOption, store SuiteTicketStore, opts ...ClientOption) (*SuiteClient, error) {
+	if store == nil {
+		store = NewMemorySuiteTicketStore()
+	}
+	suite := &SuiteClient{
+		ding:       NewClient(Option{}, opts...),
+		opt:        opt,
+		store:      store,
+		corps:      make(map[string]*Client),
+		corpTokens: make(map[string]corpToken),
+	}
+	if opt.Token != "" || opt.AESKey != "" {
+		crypto, err := NewCallbackCrypto(opt.Token, opt.AESKey, opt.SuiteKey)
+		if err != nil {
+			return nil, err
+		}
+		suite.crypto = crypto
+	}
+	return suite, nil
+}
+
+// SetSuiteTicket 保存钉钉推送的suite_ticket
+func (suite *SuiteClient) SetSuiteTicket(ctx context.Context, ticket string) error {
+	return suite.store.SetSuiteTicket(ctx, suite.opt.SuiteKey, ticket)
+}
+
+// SuiteTicket 读取最近一次推送的suite_ticket
+func (suite *SuiteClient) SuiteTicket(ctx context.Context) (string, error) {
+	ticket, err := suite.store.GetSuiteTicket(ctx, suite.opt.SuiteKey)
+	if err != nil {
+		return "", err
+	}
+	if ticket == "" {
+		return "", ErrNoSuiteTicket
+	}
+	return ticket, nil
+}
+
+// HandleCallback 解密并处理回调事件：保存suite_ticket、激活tmp_auth_code授权的企业，返回需响应给钉钉的加密报文
+func (suite *SuiteClient) HandleCallback(ctx context.Context, signature, timestamp, nonce string, req *RequestCallback) (*SuiteCallbackEvent, *ResponseCallback, error) {
+	if suite.crypto == nil {
+		return nil, nil, errors.New("no callback token or aes key provided")
+	}
+	plain, err := suite.crypto.Decrypt(signature, timestamp, nonce, req.Encrypt)
+	if err != nil {
+		return nil, nil, err
+	}
+	event := new(SuiteCallbackEvent)
+	if err = json.Unmarshal(plain, event); err != nil {
+		return nil, nil, err
+	}
+
+	switch event.EventType {
+	case SuiteEventSuiteTicket:
+		err = suite.SetSuiteTicket(ctx, event.SuiteTicket)
+	case SuiteEventTmpAuthCode:
+		event.PermanentCode, _, err = suite.ActivateByTmpAuthCode(ctx, event.AuthCode)
+	case SuiteEventRelieveAuth:
+		suite.mu.Lock()
+		delete(suite.corps, event.AuthCorpID)
+		delete(suite.corpTokens, event.AuthCorpID)
+		suite.mu.Unlock()
+	}
+	if err != nil {
+		return event, nil, err
+	}
+
+	reply, err := suite.crypto.Success()
+	return event, reply, err
+}
+
+// GetSuiteAccessToken 获取第三方企业应用的suite_access_token，未过期时直接返回缓存 https://developers.dingtalk.com/document/app/obtains-the-suite-access-token-of-third-party-enterprise-applications
+func (suite *SuiteClient) GetSuiteAccessToken(ctx context.Context) (string, *http.Response, error) {
+	if token := suite.cachedSuiteAccessToken(); token != "" {
+		return token, nil, nil
+	}
+
+	var res *http.Response
+	v, err, _ := suite.flight.Do("suite_access_token", func() (interface{}, error) {
+		defer suite.flight.Forget("suite_access_token")
+		if token := suite.cachedSuiteAccessToken(); token != "" {
+			return token, nil
+		}
+		ticket, err := suite.SuiteTicket(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		ret := new(ResponseGetSuiteAccessToken)
+		res, _, err = suite.ding.post(
+			ctx,
+			suite.ding.url+"/service/get_suite_token",
+			requests.Params{Json: requests.Any{"suite_key": suite.opt.SuiteKey, "suite_secret": suite.opt.SuiteSecret, "suite_ticket": ticket}},
+			UnmarshalAndParseError(ret),
+		)
+		if err != nil {
+			return "", err
+		}
+		suite.mu.Lock()
+		suite.token = ret.SuiteAccessToken
+		suite.tokenExpiresAt = time.Now().Add(time.Duration(ret.ExpiresIn)*time.Second - suiteAccessTokenLeeway)
+		suite.mu.Unlock()
+		return ret.SuiteAccessToken, nil
+	})
+	if err != nil {
+		return "", res, err
+	}
+	return v.(string), res, nil
+}
+
+// GetPermanentCode 使用临时授权码换取企业的永久授权码 https://developers.dingtalk.com/document/app/obtain-the-permanent-authorization-code-of-the-enterprise
+func (suite *SuiteClient) GetPermanentCode(ctx context.Context, tmpAuthCode string) (*PermanentCode, *http.Response, error) {
+	ret := new(ResponseGetPermanentCode)
+	var res *http.Response
+	var err error
+
+	err = suite.retryOnSuiteAccessTokenExpired(ctx, func(token string) error {
+		res, _, err = suite.ding.post(
+			ctx,
+			suite.ding.url+"/service/get_permanent_code",
+			requests.Params{Query: requests.Any{"suite_access_token": token}, Json: requests.Any{"tmp_auth_code": tmpAuthCode}},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return ret.PermanentCode, res, err
+}
+
+// ActivateSuite 激活授权企业的应用 https://developers.dingtalk.com/document/app/activate-suite
+func (suite *SuiteClient) ActivateSuite(ctx context.Context, authCorpID, permanentCode string) (*http.Response, error) {
+	ret := new(BasicResponse)
+	var res *http.Response
+	var err error
+
+	err = suite.retryOnSuiteAccessTokenExpired(ctx, func(token string) error {
+		res, _, err = suite.ding.post(
+			ctx,
+			suite.ding.url+"/service/activate_suite",
+			requests.Params{
+				Query: requests.Any{"suite_access_token": token},
+				Json:  requests.Any{"suite_key": suite.opt.SuiteKey, "auth_corpid": authCorpID, "permanent_code": permanentCode},
+			},
+			UnmarshalAndParseError(ret),
+		)
+		return err
+	})
+	return res, err
+}
+
+// ActivateByTmpAuthCode 处理tmp_auth_code推送：换取永久授权码并激活应用，永久授权码需由调用方自行保存
+func (suite *SuiteClient) ActivateByTmpAuthCode(ctx context.Context, tmpAuthCode string) (*PermanentCode, *http.Response, error) {
+	code, res, err := suite.GetPermanentCode(ctx, tmpAuthCode)
+	if err != nil {
+		return nil, res, err
+	}
+	if code == nil || code.AuthCorpInfo == nil {
+		return nil, res, errors.New("empty permanent code")
+	}
+	res, err = suite.ActivateSuite(ctx, code.AuthCorpInfo.CorpID, code.PermanentCode)
+	return code, res, err
+}
+
+// GetCorpToken 获取授权企业的access_token，未过期时直接返回缓存 https://developers.dingtalk.com/document/app/obtains-the-enterprise-authorized-credential
+func (suite *SuiteClient) GetCorpToken(ctx context.Context, authCorpID string) (string, *http.Response, error) {
+	if token := suite.cachedCorpToken(authCorpID); token != "" {
+		return token, nil, nil
+	}
+
+	var res *http.Response
+	key := "corp_token:" + authCorpID
+	v, err, _ := suite.flight.Do(key, func() (interface{}, error) {
+		defer suite.flight.Forget(key)
+		if token := suite.cachedCorpToken(authCorpID); token != "" {
+			return token, nil
+		}
+		ticket, err := suite.SuiteTicket(ctx)
+		if err != nil {
+			return "", err
+		}
+		ts := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
+		h := hmac.New(sha256.New, []byte(suite.opt.SuiteSecret))
+		h.Write([]byte(ts + "\n" + ticket))
+		signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+		ret := new(ResponseGetAccessToken)
+		res, _, err = suite.ding.post(
+			ctx,
+			suite.ding.url+"/service/get_corp_token",
+			requests.Params{
+				Query: requests.Any{"accessKey": suite.opt.SuiteKey, "timestamp": ts, "suiteTicket": ticket, "signature": signature},
+				Json:  requests.Any{"auth_corpid": authCorpID},
+			},
+			UnmarshalAndParseError(ret),
+		)
+		if err != nil {
+			return "", err
+		}
+		suite.mu.Lock()
+		suite.corpTokens[authCorpID] = corpToken{
+			token:     ret.AccessToken,
+			expiresAt: time.Now().Add(time.Duration(ret.ExpiresIn)*time.Second - suiteAccessTokenLeeway),
+		}
+		suite.mu.Unlock()
+		return ret.AccessToken, nil
+	})
+	if err != nil {
+		return "", res, err
+	}
+	return v.(string), res, nil
+}
+
+// CorpClient 返回授权企业的客户端，access_token通过GetCorpToken获取，同一企业复用同一个Client
+func (suite *SuiteClient) CorpClient(authCorpID string) *Client {
+	suite.mu.RLock()
+	ding, ok := suite.corps[authCorpID]
+	suite.mu.RUnlock()
+	if ok {
+		return ding
+	}
+
+	suite.mu.Lock()
+	defer suite.mu.Unlock()
+	if ding, ok = suite.corps[authCorpID]; ok {
+		return ding
+	}
+	ding = suite.ding.derive(Option{CorpID: authCorpID})
+	ding.suite = suite
+	suite.corps[authCorpID] = ding
+	return ding
+}
+
+func (suite *SuiteClient) cachedSuiteAccessToken() string {
+	suite.mu.RLock()
+	defer suite.mu.RUnlock()
+	if suite.token == "" || time.Now().After(suite.tokenExpiresAt) {
+		return ""
+	}
+	return suite.token
+}
+
+func (suite *SuiteClient) cachedCorpToken(authCorpID string) string {
+	suite.mu.RLock()
+	defer suite.mu.RUnlock()
+	ct, ok := suite.corpTokens[authCorpID]
+	if !ok || time.Now().After(ct.expiresAt) {
+		return ""
+	}
+	return ct.token
+}
+
+// forgetCorpToken 授权企业的access_token被钉钉判定为过期时丢弃缓存，token已被其他请求刷新时保留
+func (suite *SuiteClient) forgetCorpToken(authCorpID, token string) {
+	suite.mu.Lock()
+	defer suite.mu.Unlock()
+	if ct, ok := suite.corpTokens[authCorpID]; ok && ct.token == token {
+		delete(suite.corpTokens, authCorpID)
+	}
+}
+
+func (suite *SuiteClient) retryOnSuiteAccessTokenExpired(ctx context.Context, fn func(token string) error) error {
+	token, _, err := suite.GetSuiteAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(token)
+	var de *DingtalkErr
+	if errors.As(err, &de) && de.IsAccessTokenExpired() {
+		suite.mu.Lock()
+		suite.token = ""
+		suite.mu.Unlock()
+		if token, _, err = suite.GetSuiteAccessToken(ctx); err != nil {
+			return err
+		}
+		return fn(token)
+	}
+	return err
+}
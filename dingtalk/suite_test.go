@@ -0,0 +1,194 @@
+package dingtalk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAESKey = "o1w0aum42yaptlz8alnhwikjd3jenzt9cb9wmzptgus"
+
+// fakeSuite 模拟第三方企业应用的授权接口
+type fakeSuite struct {
+	mu          sync.Mutex
+	calls       map[string]int
+	suiteTokens int
+	corpTokens  int
+	activated   map[string]string
+}
+
+func (fs *fakeSuite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.calls[r.URL.Path]++
+	var body map[string]string
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	write := func(v interface{}) { _ = json.NewEncoder(w).Encode(v) }
+
+	switch r.URL.Path {
+	case "/service/get_suite_token":
+		if body["suite_ticket"] != "ticket" || body["suite_secret"] != "secret" {
+			write(map[string]interface{}{"errcode": 40078, "errmsg": "不存在的临时授权码"})
+			return
+		}
+		fs.suiteTokens++
+		write(map[string]interface{}{"errcode": 0, "suite_access_token": "suite-token", "expires_in": 7200})
+	case "/service/get_permanent_code":
+		if r.URL.Query().Get("suite_access_token") != "suite-token" {
+			write(map[string]interface{}{"errcode": 40014, "errmsg": "不合法的access_token"})
+			return
+		}
+		write(map[string]interface{}{
+			"errcode":        0,
+			"permanent_code": "permanent-" + body["tmp_auth_code"],
+			"auth_corp_info": map[string]string{"corpid": "corp1", "corp_name": "测试企业"},
+		})
+	case "/service/activate_suite":
+		fs.activated[body["auth_corpid"]] = body["permanent_code"]
+		write(map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+	case "/service/get_corp_token":
+		q := r.URL.Query()
+		h := hmac.New(sha256.New, []byte("secret"))
+		h.Write([]byte(q.Get("timestamp") + "\n" + q.Get("suiteTicket")))
+		if q.Get("signature") != base64.StdEncoding.EncodeToString(h.Sum(nil)) {
+			write(map[string]interface{}{"errcode": 40001, "errmsg": "签名错误"})
+			return
+		}
+		fs.corpTokens++
+		write(map[string]interface{}{"errcode": 0, "access_token": "corp-token-" + body["auth_corpid"], "expires_in": 7200})
+	case "/user/get_org_user_count":
+		if r.URL.Query().Get("access_token") != "corp-token-corp1" {
+			write(map[string]interface{}{"errcode": 40014, "errmsg": "不合法的access_token"})
+			return
+		}
+		write(map[string]interface{}{"errcode": 0, "count": 7})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestSuite(t *testing.T) (*SuiteClient, *fakeSuite, func()) {
+	fs := &fakeSuite{calls: map[string]int{}, activated: map[string]string{}}
+	srv := httptest.NewServer(fs)
+	suite, err := NewSuiteClient(
+		SuiteOption{SuiteKey: "suite_key", SuiteSecret: "secret", Token: "token", AESKey: testAESKey},
+		nil,
+		WithBaseURL(srv.URL), WithRetryPolicy(fastRetry), WithRateLimiter(nil),
+	)
+	assert.Nil(t, err)
+	return suite, fs, srv.Close
+}
+
+// encryptCallback 按钉钉的方式加密回调事件
+func encryptCallback(t *testing.T, event string) *ResponseCallback {
+	cc, err := NewCallbackCrypto("token", testAESKey, "suite_key")
+	assert.Nil(t, err)
+	enc, err := cc.Encrypt([]byte(event))
+	assert.Nil(t, err)
+	return enc
+}
+
+func handleCallback(t *testing.T, suite *SuiteClient, event string) (*SuiteCallbackEvent, *ResponseCallback, error) {
+	enc := encryptCallback(t, event)
+	return suite.HandleCallback(context.Background(), enc.MsgSignature, enc.TimeStamp, enc.Nonce, &RequestCallback{Encrypt: enc.Encrypt})
+}
+
+func TestSuiteClient_GetSuiteAccessToken(t *testing.T) {
+	suite, fs, closeFn := newTestSuite(t)
+	defer closeFn()
+	ctx := context.Background()
+
+	_, _, err := suite.GetSuiteAccessToken(ctx)
+	assert.Equal(t, ErrNoSuiteTicket, err)
+
+	assert.Nil(t, suite.SetSuiteTicket(ctx, "ticket"))
+	token, _, err := suite.GetSuiteAccessToken(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "suite-token", token)
+	token, res, err := suite.GetSuiteAccessToken(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, "suite-token", token)
+	assert.Equal(t, 1, fs.suiteTokens)
+}
+
+func TestSuiteClient_HandleCallback(t *testing.T) {
+	suite, fs, closeFn := newTestSuite(t)
+	defer closeFn()
+	ctx := context.Background()
+
+	event, reply, err := handleCallback(t, suite, `{"EventType":"suite_ticket","SuiteKey":"suite_key","SuiteTicket":"ticket"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, SuiteEventSuiteTicket, event.EventType)
+	assert.NotEmpty(t, reply.Encrypt)
+	ticket, err := suite.SuiteTicket(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "ticket", ticket)
+
+	event, _, err = handleCallback(t, suite, `{"EventType":"tmp_auth_code","AuthCode":"code"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, "permanent-code", event.PermanentCode.PermanentCode)
+	assert.Equal(t, "corp1", event.PermanentCode.AuthCorpInfo.CorpID)
+	assert.Equal(t, map[string]string{"corp1": "permanent-code"}, fs.activated)
+
+	enc := encryptCallback(t, `{"EventType":"check_url"}`)
+	_, _, err = suite.HandleCallback(ctx, "bad"+enc.MsgSignature, enc.TimeStamp, enc.Nonce, &RequestCallback{Encrypt: enc.Encrypt})
+	assert.Equal(t, ErrCallbackSignature, err)
+}
+
+func TestSuiteClient_GetPermanentCode_SuiteTokenExpired(t *testing.T) {
+	suite, fs, closeFn := newTestSuite(t)
+	defer closeFn()
+	ctx := context.Background()
+	assert.Nil(t, suite.SetSuiteTicket(ctx, "ticket"))
+
+	// 缓存的suite_access_token已失效时刷新后重试
+	suite.token, suite.tokenExpiresAt = "stale", time.Now().Add(time.Hour)
+	code, _, err := suite.GetPermanentCode(ctx, "code")
+	assert.Nil(t, err)
+	assert.Equal(t, "permanent-code", code.PermanentCode)
+	assert.Equal(t, 2, fs.calls["/service/get_permanent_code"])
+	assert.Equal(t, 1, fs.suiteTokens)
+}
+
+func TestSuiteClient_CorpClient(t *testing.T) {
+	suite, fs, closeFn := newTestSuite(t)
+	defer closeFn()
+	ctx := context.Background()
+	assert.Nil(t, suite.SetSuiteTicket(ctx, "ticket"))
+
+	corp := suite.CorpClient("corp1")
+	assert.Same(t, corp, suite.CorpClient("corp1"))
+	count, _, err := corp.GetOrganizationUserCount(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, count)
+
+	// access_token按企业缓存，同一企业的其他客户端不会重复获取
+	token, res, err := suite.GetCorpToken(ctx, "corp1")
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, "corp-token-corp1", token)
+	assert.Equal(t, 1, fs.corpTokens)
+
+	// 钉钉判定access_token过期时丢弃缓存重新获取
+	corp.SetAccessToken("stale")
+	suite.corpTokens["corp1"] = corpToken{token: "stale", expiresAt: suite.corpTokens["corp1"].expiresAt}
+	count, _, err = corp.GetOrganizationUserCount(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, count)
+	assert.Equal(t, 2, fs.corpTokens)
+
+	_, _, err = handleCallback(t, suite, `{"EventType":"suite_relieve","AuthCorpId":"corp1"}`)
+	assert.Nil(t, err)
+	assert.Empty(t, suite.cachedCorpToken("corp1"))
+	assert.NotSame(t, corp, suite.CorpClient("corp1"))
+}
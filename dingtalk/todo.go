@@ -0,0 +1,236 @@
+package dingtalk
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/jacexh/requests"
+)
+
+type (
+	// TodoPriority 待办优先级
+	TodoPriority int
+
+	TodoDetailURL struct {
+		AppURL string `json:"appUrl,omitempty"`
+		PCURL  string `json:"pcUrl,omitempty"`
+	}
+
+	// TodoTask 待办任务，ExecutorIDs、ParticipantIDs、CreatorID均为unionId
+	TodoTask struct {
+		ID                 string         `json:"id,omitempty"`
+		SourceID           string         `json:"sourceId,omitempty"` // 业务系统侧的唯一标识，用于幂等创建
+		Subject            string         `json:"subject,omitempty"`
+		Description        string         `json:"description,omitempty"`
+		CreatorID          string         `json:"creatorId,omitempty"`
+		ExecutorIDs        []string       `json:"executorIds,omitempty"`
+		ParticipantIDs     []string       `json:"participantIds,omitempty"`
+		DetailURL          *TodoDetailURL `json:"detailUrl,omitempty"`
+		DueTime            *UnixTimestamp `json:"dueTime,omitempty"`
+		Priority           TodoPriority   `json:"priority,omitempty"`
+		IsOnlyShowExecutor bool           `json:"isOnlyShowExecutor,omitempty"`
+		Done               bool           `json:"done,omitempty"`
+		StartTime          *UnixTimestamp `json:"startTime,omitempty"`
+		FinishTime         *UnixTimestamp `json:"finishTime,omitempty"`
+		CreatedTime        *UnixTimestamp `json:"createdTime,omitempty"`
+		ModifiedTime       *UnixTimestamp `json:"modifiedTime,omitempty"`
+		Source             string         `json:"source,omitempty"`
+		BizTag             string         `json:"bizTag,omitempty"`
+	}
+
+	// RequestUpdateTodoTask 更新待办，nil字段不做修改
+	RequestUpdateTodoTask struct {
+		Subject        *string        `json:"subject,omitempty"`
+		Description    *string        `json:"description,omitempty"`
+		DueTime        *UnixTimestamp `json:"dueTime,omitempty"`
+		Done           *bool          `json:"done,omitempty"`
+		ExecutorIDs    []string       `json:"executorIds,omitempty"`
+		ParticipantIDs []string       `json:"participantIds,omitempty"`
+	}
+
+	// TodoCard 待办列表中的卡片
+	TodoCard struct {
+		TaskID       string         `json:"taskId"`
+		SourceID     string         `json:"sourceId,omitempty"`
+		Subject      string         `json:"subject"`
+		CreatorID    string         `json:"creatorId,omitempty"`
+		DetailURL    *TodoDetailURL `json:"detailUrl,omitempty"`
+		DueTime      *UnixTimestamp `json:"dueTime,omitempty"`
+		Priority     TodoPriority   `json:"priority,omitempty"`
+		IsDone       bool           `json:"isDone"`
+		CreatedTime  *UnixTimestamp `json:"createdTime,omitempty"`
+		ModifiedTime *UnixTimestamp `json:"modifiedTime,omitempty"`
+		BizTag       string         `json:"bizTag,omitempty"`
+	}
+
+	// RequestListTodoTasks 查询待办列表，IsDone为nil时查询全部
+	RequestListTodoTasks struct {
+		NextToken string `json:"nextToken,omitempty"`
+		IsDone    *bool  `json:"isDone,omitempty"`
+	}
+
+	TodoCardPage struct {
+		TodoCards []*TodoCard `json:"todoCards"`
+		NextToken string      `json:"nextToken,omitempty"`
+	}
+
+	ResponseTodoResult struct {
+		Result bool `json:"result"`
+	}
+)
+
+const (
+	TodoPriorityLow        TodoPriority = 10
+	TodoPriorityNormal     TodoPriority = 20
+	TodoPriorityUrgent     TodoPriority = 30
+	TodoPriorityVeryUrgent TodoPriority = 40
+)
+
+func (ding *Client) todoURL(unionID string, segments ...string) string {
+	u := ding.api + "/v1.0/todo/users/" + url.PathEscape(unionID) + "/tasks"
+	for _, seg := range segments {
+		u += "/" + url.PathEscape(seg)
+	}
+	return u
+}
+
+// CreateTodoTask 创建待办，unionID为操作者。设置了SourceID时，已存在相同SourceID的待办则直接返回该待办 https://developers.dingtalk.com/document/app/add-dingtalk-to-do-task
+func (ding *Client) CreateTodoTask(ctx context.Context, unionID string, task *TodoTask) (*TodoTask, *http.Response, error) {
+	if task.SourceID == "" {
+		return ding.createTodoTask(ctx, unionID, task)
+	}
+
+	var res *http.Response
+	v, err, _ := ding.flight.Do("todo:"+unionID+":"+task.SourceID, func() (interface{}, error) {
+		defer ding.flight.Forget("todo:" + unionID + ":" + task.SourceID)
+		existing, r, err := ding.GetTodoTaskBySourceID(ctx, unionID, task.SourceID)
+		res = r
+		if err == nil {
+			return existing, nil
+		}
+		if !IsNotFound(err) {
+			return nil, err
+		}
+		created, r, err := ding.createTodoTask(ctx, unionID, task)
+		res = r
+		return created, err
+	})
+	if err != nil {
+		return nil, res, err
+	}
+	return v.(*TodoTask), res, nil
+}
+
+func (ding *Client) createTodoTask(ctx context.Context, unionID string, task *TodoTask) (*TodoTask, *http.Response, error) {
+	ret := new(TodoTask)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, nonIdempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.todoURL(unionID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Query: requests.Any{"operatorId": unionID}, Json: task},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret, res, err
+}
+
+// GetTodoTask 查询待办详情 https://developers.dingtalk.com/document/app/query-dingtalk-to-do-task-details
+func (ding *Client) GetTodoTask(ctx context.Context, unionID, taskID string) (*TodoTask, *http.Response, error) {
+	ret := new(TodoTask)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.get(
+			ctx,
+			ding.todoURL(unionID, taskID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken())},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret, res, err
+}
+
+// GetTodoTaskBySourceID 根据业务系统的SourceID查询待办 https://developers.dingtalk.com/document/app/query-to-do-tasks-based-on-the-source-id
+func (ding *Client) GetTodoTaskBySourceID(ctx context.Context, unionID, sourceID string) (*TodoTask, *http.Response, error) {
+	ret := new(TodoTask)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.get(
+			ctx,
+			ding.todoURL(unionID, "sources", sourceID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken())},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret, res, err
+}
+
+// UpdateTodoTask 更新待办 https://developers.dingtalk.com/document/app/updates-dingtalk-to-do-tasks
+func (ding *Client) UpdateTodoTask(ctx context.Context, unionID, taskID string, req *RequestUpdateTodoTask) (bool, *http.Response, error) {
+	ret := new(ResponseTodoResult)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.put(
+			ctx,
+			ding.todoURL(unionID, taskID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Query: requests.Any{"operatorId": unionID}, Json: req},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret.Result, res, err
+}
+
+// CompleteTodoTask 将待办标记为已完成
+func (ding *Client) CompleteTodoTask(ctx context.Context, unionID, taskID string) (bool, *http.Response, error) {
+	done := true
+	return ding.UpdateTodoTask(ctx, unionID, taskID, &RequestUpdateTodoTask{Done: &done})
+}
+
+// DeleteTodoTask 删除待办 https://developers.dingtalk.com/document/app/delete-dingtalk-to-do-task
+func (ding *Client) DeleteTodoTask(ctx context.Context, unionID, taskID string) (bool, *http.Response, error) {
+	ret := new(ResponseTodoResult)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.delete(
+			ctx,
+			ding.todoURL(unionID, taskID),
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Query: requests.Any{"operatorId": unionID}},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret.Result, res, err
+}
+
+// ListTodoTasks 查询企业下用户的待办列表 https://developers.dingtalk.com/document/app/query-the-to-do-list-of-enterprise-users
+func (ding *Client) ListTodoTasks(ctx context.Context, unionID string, req *RequestListTodoTasks) (*TodoCardPage, *http.Response, error) {
+	ret := new(TodoCardPage)
+	var res *http.Response
+	var err error
+
+	err = ding.withRetry(ctx, idempotent, func(ctx context.Context) error {
+		res, _, err = ding.post(
+			ctx,
+			ding.api+"/v1.0/todo/users/"+url.PathEscape(unionID)+"/org/tasks/query",
+			requests.Params{Headers: gatewayHeaders(ding.AccessToken()), Json: req},
+			UnmarshalGatewayResponse(ret),
+		)
+		return err
+	})
+	return ret, res, err
+}
@@ -0,0 +1,68 @@
+package dingtalk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_CreateTodoTask_Idempotent(t *testing.T) {
+	var mu sync.Mutex
+	tasks := map[string]*TodoTask{}
+	created := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1.0/todo/users/union/tasks/sources/ticket-1":
+			if task, ok := tasks["ticket-1"]; ok {
+				_ = json.NewEncoder(w).Encode(task)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":"taskNotExist","message":"task not exist"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1.0/todo/users/union/tasks":
+			task := new(TodoTask)
+			_ = json.NewDecoder(r.Body).Decode(task)
+			created++
+			task.ID = "task-1"
+			tasks[task.SourceID] = task
+			_ = json.NewEncoder(w).Encode(task)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	ding := NewClient(Option{AppKey: "key", AppSecret: "secret"}, WithGatewayURL(srv.URL))
+	task := &TodoTask{
+		SourceID:    "ticket-1",
+		Subject:     "fix bug",
+		ExecutorIDs: []string{"union"},
+		DueTime:     NewUnixTimestamp(time.Now().Add(time.Hour)),
+		Priority:    TodoPriorityUrgent,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ret, _, err := ding.CreateTodoTask(context.Background(), "union", task)
+			assert.Nil(t, err)
+			assert.Equal(t, "task-1", ret.ID)
+		}()
+	}
+	wg.Wait()
+	ret, _, err := ding.CreateTodoTask(context.Background(), "union", task)
+	assert.Nil(t, err)
+	assert.Equal(t, "task-1", ret.ID)
+	assert.Equal(t, 1, created)
+}
@@ -0,0 +1,86 @@
+package dingtalk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/jacexh/requests"
+)
+
+// do 所有请求的统一出口：由requests.Session组装请求，经由中间件包装后的http.Client发送
+func (ding *Client) do(ctx context.Context, method, path string, params requests.Params, interceptor requests.Interceptor) (*http.Response, []byte, error) {
+	req, err := ding.client.Prepare(ctx, method, path, params, new(bytes.Buffer), true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = ding.limiter.Wait(ctx, ding.rateLimitApp(), rateLimitPath(req.URL)); err != nil {
+		return nil, nil, err
+	}
+	res, err := ding.httpClient.Do(req)
+	recordAttempt(ctx, req, res)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return res, nil, err
+	}
+	if interceptor != nil {
+		err = interceptor(req, res, data)
+	}
+	return res, data, annotateError(err, req, res, data)
+}
+
+func (ding *Client) get(ctx context.Context, path string, params requests.Params, interceptor requests.Interceptor) (*http.Response, []byte, error) {
+	return ding.do(ctx, http.MethodGet, path, params, interceptor)
+}
+
+func (ding *Client) post(ctx context.Context, path string, params requests.Params, interceptor requests.Interceptor) (*http.Response, []byte, error) {
+	return ding.do(ctx, http.MethodPost, path, params, interceptor)
+}
+
+func (ding *Client) put(ctx context.Context, path string, params requests.Params, interceptor requests.Interceptor) (*http.Response, []byte, error) {
+	return ding.do(ctx, http.MethodPut, path, params, interceptor)
+}
+
+func (ding *Client) delete(ctx context.Context, path string, params requests.Params, interceptor requests.Interceptor) (*http.Response, []byte, error) {
+	return ding.do(ctx, http.MethodDelete, path, params, interceptor)
+}
+
+// rateLimitApp 限流使用的应用标识，第三方企业应用模式下以授权企业区分
+func (ding *Client) rateLimitApp() string {
+	opt := ding.Option()
+	return opt.AppKey + "/" + opt.CorpID
+}
+
+// annotateError 为错误附加接口路径、HTTP状态码及request_id，便于排查。
+// 旧版接口非2xx时响应体中的errcode不可信（如网关返回{"errcode":0}），一律返回HTTPError
+func annotateError(err error, req *http.Request, res *http.Response, data []byte) error {
+	var ge *GatewayErr
+	if errors.As(err, &ge) {
+		ge.Path = req.URL.Path
+		return err
+	}
+	if res.StatusCode >= 300 {
+		return &HTTPError{StatusCode: res.StatusCode, Path: req.URL.Path, Body: data}
+	}
+	var de *DingtalkErr
+	if errors.As(err, &de) {
+		de.Path = req.URL.Path
+		de.StatusCode = res.StatusCode
+		if de.RequestID == "" {
+			var body struct {
+				RequestID string `json:"request_id"`
+			}
+			if json.Unmarshal(data, &body) == nil {
+				de.RequestID = body.RequestID
+			}
+		}
+	}
+	return err
+}
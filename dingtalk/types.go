@@ -409,6 +409,11 @@ type (
 	}
 )
 
+// NewUnixTimestamp 将time.Time转换为毫秒时间戳
+func NewUnixTimestamp(t time.Time) *UnixTimestamp {
+	return &UnixTimestamp{ts: t.UnixNano() / 1e6}
+}
+
 func (ts *UnixTimestamp) UnmarshalJSON(data []byte) error {
 	var t int64
 	if err := json.Unmarshal(data, &t); err != nil {
@@ -1,6 +1,7 @@
 package dingtalk
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 )
@@ -9,3 +10,30 @@ func TestUnixTimestamp_Time(t *testing.T) {
 	u := &UnixTimestamp{ts: 1597573616828}
 	fmt.Println(u.Time().String())
 }
+
+func TestSignJSAPITicket(t *testing.T) {
+	sign, err := SignJSAPITicket("abc", "nonce", 1414587457, "http%3A%2F%2Fwww.dingtalk.com%2F%3Fa%3Db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sign != "e9dd8ac8cf7663ace904e7f9e2d005d56dc050b5" {
+		t.Fatalf("unexpected signature: %s", sign)
+	}
+}
+
+func TestReport_Sections(t *testing.T) {
+	report := new(Report)
+	err := json.Unmarshal([]byte(`{"report_id":"r","create_time":1597573616828,"contents":[{"key":"今日完成工作","value":"联调","sort":"0","type":"1"},{"key":"明日计划","value":"上线","sort":"1","type":"1"}]}`), report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := report.Value("明日计划"); !ok || v != "上线" {
+		t.Fatalf("unexpected value: %s", v)
+	}
+	if len(report.Sections()) != 2 {
+		t.Fatal("expect 2 sections")
+	}
+	if report.CreateTime.Time().Unix() != 1597573616 {
+		t.Fatal("bad create time")
+	}
+}
@@ -15,3 +15,28 @@ func UnmarshalAndParseError(v Response) requests.Interceptor {
 		return v.GotErr()
 	}
 }
+
+// UnmarshalGatewayResponse 解析新版服务端API的响应，HTTP状态码非2xx时返回GatewayErr
+func UnmarshalGatewayResponse(v interface{}) requests.Interceptor {
+	return func(request *http.Request, response *http.Response, bytes []byte) error {
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			ge := &GatewayErr{StatusCode: response.StatusCode}
+			if len(bytes) > 0 {
+				_ = json.Unmarshal(bytes, ge)
+			}
+			if ge.Code == "" {
+				ge.Code = http.StatusText(response.StatusCode)
+			}
+			return ge
+		}
+		if v == nil || len(bytes) == 0 {
+			return nil
+		}
+		return json.Unmarshal(bytes, v)
+	}
+}
+
+// gatewayHeaders 新版服务端API通过请求头传递access_token
+func gatewayHeaders(token string) requests.Any {
+	return requests.Any{"x-acs-dingtalk-access-token": token}
+}
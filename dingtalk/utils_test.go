@@ -0,0 +1,30 @@
+package dingtalk
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalGatewayResponse(t *testing.T) {
+	event := new(CalendarEvent)
+	err := UnmarshalGatewayResponse(event)(nil, &http.Response{StatusCode: http.StatusOK}, []byte(`{"id":"event","summary":"interview"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "interview", event.Summary)
+
+	err = UnmarshalGatewayResponse(nil)(nil, &http.Response{StatusCode: http.StatusOK}, nil)
+	assert.Nil(t, err)
+
+	err = UnmarshalGatewayResponse(event)(nil, &http.Response{StatusCode: http.StatusUnauthorized},
+		[]byte(`{"code":"InvalidAuthentication","message":"不合法的access_token","requestid":"req"}`))
+	var ge *GatewayErr
+	assert.True(t, errors.As(err, &ge))
+	assert.Equal(t, "req", ge.RequestID)
+	assert.True(t, isAccessTokenExpired(err))
+
+	err = UnmarshalGatewayResponse(event)(nil, &http.Response{StatusCode: http.StatusBadGateway}, []byte("<html></html>"))
+	assert.True(t, errors.As(err, &ge))
+	assert.Equal(t, http.StatusBadGateway, ge.StatusCode)
+}
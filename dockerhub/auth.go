@@ -0,0 +1,317 @@
+package dockerhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHost 官方仓库地址
+	DefaultHost = "registry-1.docker.io"
+
+	// tokenLeeway 提前刷新token的时间
+	tokenLeeway = 10 * time.Second
+	// defaultTokenExpiresIn token服务未返回expires_in时的有效期 https://docs.docker.com/registry/spec/auth/token/#token-response-fields
+	defaultTokenExpiresIn = 60
+)
+
+type (
+	// Challenge 仓库返回的WWW-Authenticate鉴权质询
+	Challenge struct {
+		Scheme     string // Basic或Bearer
+		Parameters map[string]string
+	}
+
+	// ResponseToken token服务的响应报文
+	ResponseToken struct {
+		Token       string    `json:"token"`
+		AccessToken string    `json:"access_token"`
+		ExpiresIn   int       `json:"expires_in"`
+		IssuedAt    time.Time `json:"issued_at"`
+	}
+
+	bearerToken struct {
+		token     string
+		expiresAt time.Time
+	}
+
+	// authTransport 处理仓库的鉴权质询：收到401时按WWW-Authenticate获取token（或使用Basic认证）后重试原请求，
+	// token按realm、service及scope缓存至过期，同一仓库的后续请求直接携带
+	authTransport struct {
+		base  http.RoundTripper
+		basic string // base64编码的用户名及密码，为空时匿名获取token
+
+		mu     sync.Mutex
+		tokens map[string]*bearerToken // realm+service+scope -> token
+		hints  map[string]*Challenge   // host+仓库+读写 -> 最近一次的质询
+	}
+)
+
+func newAuthTransport(base http.RoundTripper, basic string) *authTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &authTransport{
+		base:   base,
+		basic:  basic,
+		tokens: map[string]*bearerToken{},
+		hints:  map[string]*Challenge{},
+	}
+}
+
+// RoundTrip 实现http.RoundTripper
+func (at *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hint := hintKey(req)
+	at.mu.Lock()
+	challenge := at.hints[hint]
+	at.mu.Unlock()
+
+	// 已知鉴权方式时直接携带凭证，避免每次请求都先收到401
+	var res *http.Response
+	var err error
+	if challenge != nil {
+		if authorization, ok := at.cachedAuthorization(challenge); ok {
+			res, err = at.base.RoundTrip(withAuthorization(req, authorization))
+			if err != nil || res.StatusCode != http.StatusUnauthorized || challenge.Scheme != "bearer" {
+				return res, err
+			}
+			// token被吊销、scope不足或服务端认为已过期时丢弃缓存，按新的质询重新获取
+			at.forgetToken(challenge)
+		}
+	}
+	if res == nil {
+		if res, err = at.base.RoundTrip(req); err != nil || res.StatusCode != http.StatusUnauthorized {
+			return res, err
+		}
+	}
+
+	challenge = ParseChallenge(res.Header.Get("WWW-Authenticate"))
+	if challenge == nil {
+		return res, nil
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// 请求体无法重放
+		return res, nil
+	}
+
+	authorization, err := at.authorize(req, challenge)
+	if err != nil {
+		closeResponse(res)
+		return nil, err
+	}
+	if authorization == "" {
+		return res, nil
+	}
+	closeResponse(res)
+
+	retry, err := rewind(req)
+	if err != nil {
+		return nil, err
+	}
+	at.mu.Lock()
+	at.hints[hint] = challenge
+	at.mu.Unlock()
+	return at.base.RoundTrip(withAuthorization(retry, authorization))
+}
+
+// cachedAuthorization 根据已知的质询返回可直接使用的Authorization
+func (at *authTransport) cachedAuthorization(challenge *Challenge) (string, bool) {
+	switch challenge.Scheme {
+	case "basic":
+		return "Basic " + at.basic, at.basic != ""
+	case "bearer":
+		at.mu.Lock()
+		defer at.mu.Unlock()
+		token, ok := at.tokens[challenge.tokenKey()]
+		if !ok || time.Now().After(token.expiresAt) {
+			return "", false
+		}
+		return "Bearer " + token.token, true
+	}
+	return "", false
+}
+
+func (at *authTransport) forgetToken(challenge *Challenge) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	delete(at.tokens, challenge.tokenKey())
+}
+
+// authorize 应答质询，返回空字符串时表示无法应答
+func (at *authTransport) authorize(req *http.Request, challenge *Challenge) (string, error) {
+	switch challenge.Scheme {
+	case "basic":
+		if at.basic == "" || req.Header.Get("Authorization") != "" {
+			return "", nil
+		}
+		return "Basic " + at.basic, nil
+	case "bearer":
+		token, err := at.fetchToken(req, challenge)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	}
+	return "", nil
+}
+
+// fetchToken 向realm申请token https://docs.docker.com/registry/spec/auth/token/
+func (at *authTransport) fetchToken(origin *http.Request, challenge *Challenge) (string, error) {
+	realm := challenge.Parameters["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("dockerhub: bearer challenge without realm")
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("dockerhub: bad realm %q: %w", realm, err)
+	}
+	query := u.Query()
+	if service := challenge.Parameters["service"]; service != "" {
+		query.Set("service", service)
+	}
+	for _, scope := range strings.Fields(challenge.Parameters["scope"]) {
+		query.Add("scope", scope)
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(origin.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if at.basic != "" {
+		req.Header.Set("Authorization", "Basic "+at.basic)
+	}
+	// 有效期按本地发出请求的时间计算，不使用issued_at，避免与token服务的时钟偏差导致使用已过期的token
+	requestedAt := time.Now()
+	res, err := at.base.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer closeResponse(res)
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("dockerhub: fetch token from %s: %s: %s", realm, res.Status, strings.TrimSpace(string(data)))
+	}
+
+	ret := new(ResponseToken)
+	if err = json.Unmarshal(data, ret); err != nil {
+		return "", err
+	}
+	token := ret.Token
+	if token == "" {
+		token = ret.AccessToken
+	}
+	if token == "" {
+		return "", fmt.Errorf("dockerhub: empty token from %s", realm)
+	}
+	expiresIn := ret.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = defaultTokenExpiresIn
+	}
+
+	at.mu.Lock()
+	at.tokens[challenge.tokenKey()] = &bearerToken{token: token, expiresAt: requestedAt.Add(time.Duration(expiresIn)*time.Second - tokenLeeway)}
+	at.mu.Unlock()
+	return token, nil
+}
+
+func (c *Challenge) tokenKey() string {
+	return c.Parameters["realm"] + " " + c.Parameters["service"] + " " + c.Parameters["scope"]
+}
+
+// ParseChallenge 解析WWW-Authenticate，如Bearer realm="https://auth.docker.io/token",service="registry.docker.io"，
+// 无法解析时返回nil
+func ParseChallenge(header string) *Challenge {
+	header = strings.TrimSpace(header)
+	i := strings.IndexByte(header, ' ')
+	if i < 0 {
+		if header == "" {
+			return nil
+		}
+		return &Challenge{Scheme: strings.ToLower(header), Parameters: map[string]string{}}
+	}
+	challenge := &Challenge{Scheme: strings.ToLower(header[:i]), Parameters: map[string]string{}}
+	rest := header[i+1:]
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return challenge
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			// 带引号的值中可能包含逗号，如scope="repository:foo:pull,push"
+			var b strings.Builder
+			j := 1
+			for ; j < len(rest) && rest[j] != '"'; j++ {
+				if rest[j] == '\\' && j+1 < len(rest) {
+					j++
+				}
+				b.WriteByte(rest[j])
+			}
+			value = b.String()
+			if j < len(rest) {
+				j++
+			}
+			rest = rest[j:]
+		} else if end := strings.IndexByte(rest, ','); end >= 0 {
+			value, rest = strings.TrimSpace(rest[:end]), rest[end:]
+		} else {
+			value, rest = strings.TrimSpace(rest), ""
+		}
+		challenge.Parameters[key] = value
+	}
+}
+
+// hintKey 同一仓库的读请求（GET、HEAD）与写请求所需的token scope不同，分别缓存
+func hintKey(req *http.Request) string {
+	action := "push"
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		action = "pull"
+	}
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	for _, sep := range []string{"/manifests/", "/blobs/", "/tags/"} {
+		if i := strings.Index(path, sep); i >= 0 {
+			path = path[:i]
+			break
+		}
+	}
+	return req.URL.Host + " " + path + " " + action
+}
+
+func withAuthorization(req *http.Request, authorization string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", authorization)
+	return r
+}
+
+// rewind 复制请求以便重试，请求体通过GetBody重新获取
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+func closeResponse(res *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	_ = res.Body.Close()
+}
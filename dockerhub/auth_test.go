@@ -0,0 +1,208 @@
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTokenServer 签发bearer token并校验仓库请求中的token
+type fakeTokenServer struct {
+	mu       sync.Mutex
+	seq      int
+	valid    map[string]string // token -> scope
+	fetches  int
+	issuedAt time.Time
+	basic    string // 不为空时签发token需要Basic认证
+}
+
+func (ts *fakeTokenServer) issue(w http.ResponseWriter, r *http.Request) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.basic != "" && r.Header.Get("Authorization") != "Basic "+ts.basic {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	ts.fetches++
+	ts.seq++
+	token := fmt.Sprintf("token-%d", ts.seq)
+	ts.valid[token] = strings.Join(r.URL.Query()["scope"], " ")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"token": token, "expires_in": 300, "issued_at": ts.issuedAt})
+}
+
+func (ts *fakeTokenServer) revokeAll() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.valid = map[string]string{}
+}
+
+// protect 仅放行携带有效token且scope匹配的请求
+func (ts *fakeTokenServer) protect(realm string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/v2/")
+		name = name[:strings.Index(name, "/manifests/")]
+		action := "pull"
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			action = "pull,push"
+		}
+		scope := "repository:" + name + ":" + action
+
+		ts.mu.Lock()
+		granted, ok := ts.valid[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		ts.mu.Unlock()
+		if !ok || granted != scope {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="fake",scope="%s"`, realm, scope))
+			writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newBearerRegistry(t *testing.T) (*fakeTokenServer, *fakeRegistry, *Client) {
+	ts := &fakeTokenServer{valid: map[string]string{}, issuedAt: time.Now()}
+	reg, _ := newFakeRegistry(t)
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/token", ts.issue)
+	mux.Handle("/v2/", ts.protect(srv.URL+"/token", reg))
+
+	client, err := NewClient(Option{URL: srv.URL})
+	assert.Nil(t, err)
+	return ts, reg, client
+}
+
+func TestParseChallenge(t *testing.T) {
+	c := ParseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull,push"`)
+	assert.Equal(t, "bearer", c.Scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/nginx:pull,push",
+	}, c.Parameters)
+
+	c = ParseChallenge(`Bearer realm=https://ghcr.io/token, service=ghcr.io, error="insufficient_scope"`)
+	assert.Equal(t, "https://ghcr.io/token", c.Parameters["realm"])
+	assert.Equal(t, "ghcr.io", c.Parameters["service"])
+	assert.Equal(t, "insufficient_scope", c.Parameters["error"])
+
+	c = ParseChallenge(`Basic realm="Harbor \"prod\""`)
+	assert.Equal(t, "basic", c.Scheme)
+	assert.Equal(t, `Harbor "prod"`, c.Parameters["realm"])
+
+	assert.Equal(t, &Challenge{Scheme: "basic", Parameters: map[string]string{}}, ParseChallenge("Basic"))
+	assert.Nil(t, ParseChallenge(""))
+}
+
+func TestAuthTransport_Bearer(t *testing.T) {
+	ts, reg, client := newBearerRegistry(t)
+	ctx := context.Background()
+	digest := reg.pushImage("app", "v1", map[string]string{"created": "2021-01-01T00:00:00Z"})
+
+	// 首次请求收到质询后获取token并重试
+	got, _, err := client.GetManifestDigest(ctx, "app", "v1")
+	assert.Nil(t, err)
+	assert.Equal(t, digest, got)
+	assert.Equal(t, 1, ts.fetches)
+	assert.Equal(t, 1, reg.count(http.MethodHead, "manifests"))
+
+	// 后续请求直接使用缓存的token
+	_, _, err = client.GetManifest(ctx, "app", "v1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, ts.fetches)
+
+	// 写请求需要push权限，请求体在重试时重放
+	_, _, err = client.Tag(ctx, "app", "v1", "stable")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, ts.fetches)
+	assert.Equal(t, []string{"stable", "v1"}, reg.tags("app"))
+}
+
+func TestAuthTransport_Revoked(t *testing.T) {
+	ts, reg, client := newBearerRegistry(t)
+	ctx := context.Background()
+	reg.pushImage("app", "v1", map[string]string{})
+
+	_, _, err := client.GetManifestDigest(ctx, "app", "v1")
+	assert.Nil(t, err)
+
+	// 缓存的token被拒绝时丢弃缓存并重新应答质询
+	ts.revokeAll()
+	_, _, err = client.GetManifestDigest(ctx, "app", "v1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, ts.fetches)
+	_, _, err = client.GetManifestDigest(ctx, "app", "v1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, ts.fetches)
+}
+
+func TestAuthTransport_ClockSkew(t *testing.T) {
+	ts, reg, client := newBearerRegistry(t)
+	ctx := context.Background()
+	reg.pushImage("app", "v1", map[string]string{})
+
+	// token服务的时钟落后时，按issued_at计算的有效期早已过期
+	ts.issuedAt = time.Now().Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		_, _, err := client.GetManifestDigest(ctx, "app", "v1")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 1, ts.fetches)
+}
+
+func TestAuthTransport_Credentials(t *testing.T) {
+	ts, reg, _ := newBearerRegistry(t)
+	reg.pushImage("app", "v1", map[string]string{})
+	ts.basic = "dXNlcjpwYXNz" // user:pass
+
+	srv := httptest.NewServer(nil)
+	defer srv.Close()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", ts.issue)
+	mux.Handle("/v2/", ts.protect(srv.URL+"/token", reg))
+	srv.Config.Handler = mux
+
+	anonymous, _ := NewClient(Option{URL: srv.URL})
+	_, _, err := anonymous.GetManifestDigest(context.Background(), "app", "v1")
+	assert.NotNil(t, err)
+
+	client, _ := NewClient(Option{URL: srv.URL, Username: "user", Password: "pass"})
+	_, _, err = client.GetManifestDigest(context.Background(), "app", "v1")
+	assert.Nil(t, err)
+}
+
+func TestAuthTransport_Basic(t *testing.T) {
+	reg, _ := newFakeRegistry(t)
+	reg.pushImage("app", "v1", map[string]string{})
+	var unauthorized int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			unauthorized++
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client, _ := NewClient(Option{URL: srv.URL, Username: "user", Password: "pass"})
+	for i := 0; i < 3; i++ {
+		_, _, err := client.GetManifestDigest(context.Background(), "app", "v1")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 1, unauthorized)
+
+	wrong, _ := NewClient(Option{URL: srv.URL, Username: "user", Password: "wrong"})
+	_, _, err := wrong.GetManifestDigest(context.Background(), "app", "v1")
+	assert.True(t, isErrorCode(err, ErrUnauthorized))
+}
@@ -0,0 +1,155 @@
+package dockerhub
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/jacexh/requests"
+)
+
+// maxBlobResumes GetBlob读取中断后按Range续传的最大次数
+const maxBlobResumes = 3
+
+type (
+	// blobReader 边读取边校验摘要，读取中断时从已读取的位置按Range续传
+	blobReader struct {
+		ctx      context.Context
+		client   *Client
+		name     string
+		digest   string
+		body     io.ReadCloser
+		offset   int64
+		size     int64 // 未知时为-1
+		resumes  int
+		verifier *digestVerifier
+	}
+)
+
+// GetBlob 下载blob（镜像层或镜像配置），自动跟随仓库重定向至存储后端，读取中断时按Range续传，
+// 读取完毕时校验摘要，不一致时Read返回ErrDigestMismatch而非io.EOF。调用方需关闭返回的io.ReadCloser
+// https://docs.docker.com/registry/spec/api/#pulling-a-layer
+func (client *Client) GetBlob(ctx context.Context, name, digest string) (io.ReadCloser, *http.Response, error) {
+	verifier, err := newDigestVerifier(digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := client.getBlob(ctx, name, digest, 0)
+	if err != nil {
+		return nil, res, err
+	}
+	return &blobReader{
+		ctx:      ctx,
+		client:   client,
+		name:     name,
+		digest:   digest,
+		body:     res.Body,
+		size:     res.ContentLength,
+		verifier: verifier,
+	}, res, nil
+}
+
+// GetBlobRange 从offset处开始下载blob，用于调用方自行续传，不校验摘要
+func (client *Client) GetBlobRange(ctx context.Context, name, digest string, offset int64) (io.ReadCloser, *http.Response, error) {
+	res, err := client.getBlob(ctx, name, digest, offset)
+	if err != nil {
+		return nil, res, err
+	}
+	return res.Body, res, nil
+}
+
+// StatBlob 通过HEAD请求查询blob是否存在及其大小，不存在时返回BLOB_UNKNOWN错误
+func (client *Client) StatBlob(ctx context.Context, name, digest string) (*Descriptor, *http.Response, error) {
+	raw, _, err := client.do(ctx, http.MethodHead, client.url("/v2/%s/blobs/%s", name, digest), requests.Params{}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = statusError(raw, ErrBlobUnknown); err != nil {
+		return nil, raw, err
+	}
+	d := &Descriptor{
+		MediaType: parseMediaType(raw.Header.Get("Content-Type")),
+		Digest:    raw.Header.Get("Docker-Content-Digest"),
+		Size:      raw.ContentLength,
+	}
+	if d.Digest == "" {
+		d.Digest = digest
+	}
+	return d, raw, nil
+}
+
+// getBlob 发送下载请求，offset大于0时使用Range；仓库不支持Range返回完整内容时跳过offset之前的部分
+func (client *Client) getBlob(ctx context.Context, name, digest string, offset int64) (*http.Response, error) {
+	var params requests.Params
+	if offset > 0 {
+		params.Headers = requests.Any{"Range": "bytes=" + strconv.FormatInt(offset, 10) + "-"}
+	}
+	res, err := client.stream(ctx, http.MethodGet, client.url("/v2/%s/blobs/%s", name, digest), params)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case res.StatusCode == http.StatusOK && offset > 0:
+		if _, err = io.CopyN(ioutil.Discard, res.Body, offset); err != nil {
+			_ = res.Body.Close()
+			return res, err
+		}
+		res.ContentLength -= offset
+	case res.StatusCode == http.StatusOK, res.StatusCode == http.StatusPartialContent:
+	default:
+		defer res.Body.Close()
+		return res, responseError(res, ErrBlobUnknown)
+	}
+	return res, nil
+}
+
+func (br *blobReader) Read(p []byte) (int, error) {
+	for {
+		n, err := br.body.Read(p)
+		br.offset += int64(n)
+		_, _ = br.verifier.Write(p[:n])
+
+		switch {
+		case err == nil:
+			return n, nil
+		case err == io.EOF:
+			if br.size >= 0 && br.offset < br.size {
+				err = io.ErrUnexpectedEOF
+				break
+			}
+			if verr := br.verifier.Verify(); verr != nil {
+				return n, verr
+			}
+			return n, io.EOF
+		}
+
+		// 读取中断，ctx已取消或续传次数用尽时返回错误
+		if br.ctx.Err() != nil || br.resumes >= maxBlobResumes {
+			return n, err
+		}
+		if rerr := br.resume(); rerr != nil {
+			return n, fmt.Errorf("%w (resume: %s)", err, rerr.Error())
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (br *blobReader) resume() error {
+	br.resumes++
+	_ = br.body.Close()
+	res, err := br.client.getBlob(br.ctx, br.name, br.digest, br.offset)
+	if err != nil {
+		return err
+	}
+	br.body = res.Body
+	return nil
+}
+
+func (br *blobReader) Close() error {
+	return br.body.Close()
+}
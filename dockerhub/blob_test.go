@@ -0,0 +1,98 @@
+package dockerhub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetBlob(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("layer"), 10000)
+	digest := reg.pushBlob(data)
+
+	rc, _, err := client.GetBlob(ctx, "app", digest)
+	assert.Nil(t, err)
+	got, err := ioutil.ReadAll(rc)
+	assert.Nil(t, err)
+	assert.Nil(t, rc.Close())
+	assert.Equal(t, data, got)
+
+	rc, _, err = client.GetBlobRange(ctx, "app", digest, 49990)
+	assert.Nil(t, err)
+	got, _ = ioutil.ReadAll(rc)
+	_ = rc.Close()
+	assert.Equal(t, data[49990:], got)
+
+	_, _, err = client.GetBlob(ctx, "app", Digest([]byte("missing")))
+	assert.True(t, isErrorCode(err, ErrBlobUnknown))
+}
+
+func TestClient_GetBlob_Resume(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	digest := reg.pushBlob(data)
+
+	// 传输中断后按Range续传
+	reg.cuts, reg.cutAt = 1, 30000
+	rc, _, err := client.GetBlob(ctx, "app", digest)
+	assert.Nil(t, err)
+	got, err := ioutil.ReadAll(rc)
+	assert.Nil(t, err)
+	_ = rc.Close()
+	assert.Equal(t, data, got)
+	assert.Equal(t, 2, reg.count(http.MethodGet, "blobs"))
+
+	// 仓库不支持Range时返回完整内容，跳过已读取的部分
+	reg.cuts, reg.noRange = 1, true
+	rc, _, err = client.GetBlob(ctx, "app", digest)
+	assert.Nil(t, err)
+	got, err = ioutil.ReadAll(rc)
+	assert.Nil(t, err)
+	_ = rc.Close()
+	assert.Equal(t, data, got)
+
+	// 每次续传都被中断时，续传次数用尽后返回错误
+	reg.cuts, reg.cutAt, reg.noRange = 100, 10, false
+	rc, _, err = client.GetBlob(ctx, "app", digest)
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(rc)
+	_ = rc.Close()
+	assert.NotNil(t, err)
+}
+
+func TestClient_GetBlob_DigestMismatch(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	digest := "sha256:" + strings.Repeat("0", 64)
+	reg.blobs[digest] = []byte("tampered")
+
+	rc, _, err := client.GetBlob(context.Background(), "app", digest)
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(rc)
+	_ = rc.Close()
+	assert.True(t, errors.Is(err, ErrDigestMismatch))
+
+	_, _, err = client.GetBlob(context.Background(), "app", "md5:abc")
+	assert.NotNil(t, err)
+}
+
+func TestClient_StatBlob(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	digest := reg.pushBlob([]byte("config"))
+
+	d, _, err := client.StatBlob(context.Background(), "app", digest)
+	assert.Nil(t, err)
+	assert.Equal(t, digest, d.Digest)
+	assert.EqualValues(t, 6, d.Size)
+
+	_, _, err = client.StatBlob(context.Background(), "app", Digest([]byte("missing")))
+	assert.True(t, isErrorCode(err, ErrBlobUnknown))
+}
@@ -1,27 +1,37 @@
 package dockerhub
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/jacexh/requests"
 )
 
 // Client docker registry v2 api的实现
 type Client struct {
-	Host   string
-	Scheme string
-	option Option
-	client *requests.Session
+	Host       string
+	Scheme     string
+	option     Option
+	client     *requests.Session
+	httpClient *http.Client
+	// streamClient 与httpClient共用Transport，但不限制整体超时，用于下载及上传blob等耗时较长的请求
+	streamClient *http.Client
 }
 
+const defaultTimeout = 30 * time.Second
+
 func parseURL(url string) (string, string, error) {
-	reg, err := regexp.Compile("^(?P<Scheme>http[s]?)://(?P<Host>[\\w.-]*)[/]?$")
+	reg, err := regexp.Compile("^(?P<Scheme>http[s]?)://(?P<Host>[\\w.:-]*)[/]?$")
 	if err != nil {
 		return "", "", err
 	}
@@ -36,7 +46,7 @@ func NewClient(opt Option) (*Client, error) {
 	client := &Client{option: opt}
 
 	if opt.URL == "" {
-		client.Host = ""
+		client.Host = DefaultHost
 		client.Scheme = "https"
 	} else {
 		scheme, host, err := parseURL(opt.URL)
@@ -47,48 +57,107 @@ func NewClient(opt Option) (*Client, error) {
 		client.Host = host
 	}
 
-	var session *requests.Session
-	var auth = opt.AuthToken
-	if auth == "" {
-		auth = base64.StdEncoding.EncodeToString([]byte(opt.Username + ":" + opt.Password))
+	var basic = opt.AuthToken
+	if basic == "" && opt.Username != "" {
+		basic = base64.StdEncoding.EncodeToString([]byte(opt.Username + ":" + opt.Password))
+	}
+	client.client = requests.NewSession(requests.Option{})
+
+	// 凭证不再预先携带，而是由authTransport按仓库返回的质询使用Basic认证或申请Bearer token
+	client.httpClient = &http.Client{Timeout: defaultTimeout}
+	if opt.HTTPClient != nil {
+		httpClient := *opt.HTTPClient
+		client.httpClient = &httpClient
 	}
-	session = requests.NewSession(requests.Option{Headers: requests.Any{"Authorization": fmt.Sprintf("Basic " + auth)}})
-	client.client = session
+	client.httpClient.Transport = newAuthTransport(client.httpClient.Transport, basic)
+	streamClient := *client.httpClient
+	streamClient.Timeout = 0
+	client.streamClient = &streamClient
 	return client, nil
 }
 
+// do 由requests.Session组装请求，再经由httpClient发送
+func (client *Client) do(ctx context.Context, method, path string, params requests.Params, interceptor requests.Interceptor) (*http.Response, []byte, error) {
+	req, err := client.client.Prepare(ctx, method, path, params, new(bytes.Buffer), true)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return res, nil, err
+	}
+	if interceptor != nil {
+		err = interceptor(req, res, data)
+	}
+	return res, data, err
+}
+
+// stream 发送请求但不读取响应报文，由调用方负责关闭，超时由ctx控制
+func (client *Client) stream(ctx context.Context, method, path string, params requests.Params) (*http.Response, error) {
+	req, err := client.client.Prepare(ctx, method, path, params, new(bytes.Buffer), true)
+	if err != nil {
+		return nil, err
+	}
+	return client.streamClient.Do(req)
+}
+
+// responseError 读取非2xx响应的错误信息，notFound为404且报文中没有错误信息时使用的错误码
+func responseError(res *http.Response, notFound ErrorCode) error {
+	data, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	ret := new(Response)
+	if json.Unmarshal(data, ret) == nil && ret.Error() != nil {
+		return ret.Error()
+	}
+	return statusError(res, notFound)
+}
+
 func (client *Client) url(path string, v ...interface{}) string {
 	return client.Scheme + "://" + client.Host + fmt.Sprintf(path, v...)
 }
 
-// ListTags listing image tags, pagination not work: https://github.com/docker/distribution/issues/1936
+// ListTags 分页查询镜像tag，ResponseTag.Next不为空时表示还有下一页，遍历所有tag使用TagPager或ListAllTags
+// https://docs.docker.com/registry/spec/api/#listing-image-tags
 func (client *Client) ListTags(ctx context.Context, name string, opt *ListTagsOption) (*ResponseTag, *http.Response, error) {
-	res := new(Response)
-	var params requests.Params
-	if opt != nil {
-		params.Query = map[string]string{"n": strconv.Itoa(opt.Number)}
+	res, raw, next, err := client.list(ctx, client.url("/v2/%s/tags/list", name), opt.query())
+	if err != nil {
+		return nil, raw, err
+	}
+	tags := res.ResponseTag
+	if tags == nil {
+		tags = new(ResponseTag)
 	}
+	tags.Next = next
+	return tags, raw, nil
+}
 
-	raw, _, err := client.client.GetWithContext(
-		ctx,
-		client.url("/v2/%s/tags/list", name),
-		params,
-		requests.UnmarshalJSONResponse(res))
+// ListRepositories 分页查询仓库中的存储库，ResponseRepository.Next不为空时表示还有下一页
+// https://docs.docker.com/registry/spec/api/#catalog
+func (client *Client) ListRepositories(ctx context.Context, opt *ListRepositoriesOption) (*ResponseRepository, *http.Response, error) {
+	res, raw, next, err := client.list(ctx, client.url("/v2/_catalog"), opt.query())
 	if err != nil {
-		return nil, nil, err
+		return nil, raw, err
 	}
-	if res.Error() != nil {
-		return nil, raw, res.Error()
+	repos := res.ResponseRepository
+	if repos == nil {
+		repos = new(ResponseRepository)
 	}
-	return res.ResponseTag, raw, nil
+	repos.Next = next
+	return repos, raw, nil
 }
 
-// GetManifest fetch the manifest identified by name and reference where reference can be a tag or digest
+// GetManifest fetch the manifest identified by name and reference where reference can be a tag or digest，
+// 支持schema1、schema2、manifest list、OCI image manifest及OCI image index
 func (client *Client) GetManifest(ctx context.Context, name, reference string) (*ResponseManifest, *http.Response, error) {
 	res := new(Response)
-	raw, _, err := client.client.GetWithContext(ctx,
+	raw, data, err := client.do(ctx,
+		http.MethodGet,
 		client.url("/v2/%s/manifests/%s", name, reference),
-		requests.Params{},
+		requests.Params{Headers: requests.Any{"Accept": manifestAccept}},
 		requests.UnmarshalJSONResponse(res),
 	)
 	if err != nil {
@@ -97,13 +166,63 @@ func (client *Client) GetManifest(ctx context.Context, name, reference string) (
 	if res.Error() != nil {
 		return nil, raw, res.Error()
 	}
-	return res.ResponseManifest, raw, nil
+	manifest := res.ResponseManifest
+	if manifest == nil {
+		manifest = new(ResponseManifest)
+	}
+	manifest.Raw = data
+	manifest.Digest = raw.Header.Get("Docker-Content-Digest")
+	if manifest.MediaType == "" {
+		manifest.MediaType = parseMediaType(raw.Header.Get("Content-Type"))
+	}
+	if err = manifest.verify(reference); err != nil {
+		return nil, raw, err
+	}
+	return manifest, raw, nil
+}
+
+// GetManifestDigest 通过HEAD请求获取manifest的digest，不计入Docker Hub的拉取次数限制
+func (client *Client) GetManifestDigest(ctx context.Context, name, reference string) (string, *http.Response, error) {
+	raw, _, err := client.do(ctx,
+		http.MethodHead,
+		client.url("/v2/%s/manifests/%s", name, reference),
+		requests.Params{Headers: requests.Any{"Accept": manifestAccept}},
+		nil,
+	)
+	if err != nil {
+		return "", nil, err
+	}
+	if err = statusError(raw, ErrManifestUnknown); err != nil {
+		return "", raw, err
+	}
+	digest := raw.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", raw, errors.New("dockerhub: missing Docker-Content-Digest")
+	}
+	return digest, raw, nil
 }
 
 func (e Error) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// statusError 无响应报文的请求（如HEAD）根据HTTP状态码返回错误，notFound为404对应的错误码
+func statusError(res *http.Response, notFound ErrorCode) error {
+	if res.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	e := Error{Code: ErrorCode(strconv.Itoa(res.StatusCode)), Message: res.Status}
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		e.Code = notFound
+	case http.StatusUnauthorized:
+		e.Code = ErrUnauthorized
+	case http.StatusForbidden:
+		e.Code = ErrDenied
+	}
+	return e
+}
+
 // Response 判断response是否异常，如果有，返回第一个error
 func (res *Response) Error() error {
 	if len(res.Errors) > 0 {
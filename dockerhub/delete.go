@@ -0,0 +1,285 @@
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/jacexh/requests"
+)
+
+// resolveConcurrency 查询tag对应digest时的并发数
+const resolveConcurrency = 8
+
+var (
+	// ErrProtectedTag 删除manifest会同时删除受保护的tag
+	ErrProtectedTag = errors.New("dockerhub: manifest is referenced by protected tags")
+	// ErrTagsChanged 引用manifest的tag与DeleteManifestOption.ExpectedTags不一致
+	ErrTagsChanged = errors.New("dockerhub: tags referencing the manifest have changed")
+)
+
+type (
+	// DeleteManifestOption 删除manifest的安全检查配置
+	DeleteManifestOption struct {
+		ProtectedTags []string              // 受保护的tag
+		Protected     func(tag string) bool // 自定义受保护的tag，与ProtectedTags任一满足即受保护
+		Force         bool                  // 忽略受保护tag检查，强制删除
+		DryRun        bool                  // 仅检查，不执行删除
+		ExpectedTags  []string              // 不为空时，引用该digest的tag须与之完全一致，否则返回ErrTagsChanged，Force时同样检查
+	}
+
+	// DeleteManifestResult 删除结果，DryRun时为删除计划
+	DeleteManifestResult struct {
+		Digest        string
+		Tags          []string // 指向该digest的tag，删除manifest时会被一并删除
+		IndexTags     []string // 通过manifest list或OCI image index引用该digest的tag，删除后这些tag将缺少对应平台的镜像
+		ProtectedTags []string // 以上tag中受保护的tag
+		Deleted       bool
+	}
+
+	// tagRef tag指向的manifest
+	tagRef struct {
+		digest   string
+		children []string // tag指向manifest list或OCI image index时，其中各平台manifest的digest
+	}
+
+	tagRefs map[string]tagRef // tag -> tagRef
+)
+
+func (opt *DeleteManifestOption) isProtected(tag string) bool {
+	if opt == nil {
+		return false
+	}
+	for _, t := range opt.ProtectedTags {
+		if t == tag {
+			return true
+		}
+	}
+	return opt.Protected != nil && opt.Protected(tag)
+}
+
+// DeleteManifest 按digest删除manifest。v2接口会同时删除所有指向该digest的tag，
+// 因此删除前先找出这些tag以及通过manifest list或OCI image index引用该digest的tag，
+// 其中有受保护的tag时返回ErrProtectedTag，除非opt.Force为true。
+// 仓库需开启删除功能 https://docs.docker.com/registry/spec/api/#deleting-an-image
+func (client *Client) DeleteManifest(ctx context.Context, name, digest string, opt *DeleteManifestOption) (*DeleteManifestResult, *http.Response, error) {
+	if !isDigest(digest) {
+		return nil, nil, fmt.Errorf("dockerhub: manifest must be deleted by digest, got %q", digest)
+	}
+	refs, err := client.resolveTags(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client.deleteWithRefs(ctx, name, digest, refs, opt)
+}
+
+// deleteWithRefs 按已查询的tag引用检查并删除manifest，批量删除时多个digest共用一次resolveTags的结果
+func (client *Client) deleteWithRefs(ctx context.Context, name, digest string, refs tagRefs, opt *DeleteManifestOption) (*DeleteManifestResult, *http.Response, error) {
+	ret := &DeleteManifestResult{Digest: digest}
+	tags := refs.tags(digest)
+	for _, tag := range tags {
+		if refs[tag].digest == digest {
+			ret.Tags = append(ret.Tags, tag)
+		} else {
+			ret.IndexTags = append(ret.IndexTags, tag)
+		}
+		if opt.isProtected(tag) {
+			ret.ProtectedTags = append(ret.ProtectedTags, tag)
+		}
+	}
+	if opt != nil && len(opt.ExpectedTags) > 0 && !sameTags(tags, opt.ExpectedTags) {
+		return ret, nil, fmt.Errorf("%w: %s@%s is referenced by %v, expected %v", ErrTagsChanged, name, digest, tags, opt.ExpectedTags)
+	}
+	if len(ret.ProtectedTags) > 0 && (opt == nil || !opt.Force) {
+		return ret, nil, fmt.Errorf("%w: %s@%s is referenced by %v", ErrProtectedTag, name, digest, ret.ProtectedTags)
+	}
+	if opt != nil && opt.DryRun {
+		return ret, nil, nil
+	}
+
+	raw, err := client.deleteManifest(ctx, name, digest)
+	if err != nil {
+		return ret, raw, err
+	}
+	ret.Deleted = true
+	return ret, raw, nil
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, tag := range a {
+		set[tag] = true
+	}
+	for _, tag := range b {
+		if !set[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// deleteManifest 直接删除manifest，不做安全检查
+func (client *Client) deleteManifest(ctx context.Context, name, digest string) (*http.Response, error) {
+	raw, data, err := client.do(ctx, http.MethodDelete, client.url("/v2/%s/manifests/%s", name, digest), requests.Params{}, nil)
+	if err != nil {
+		return raw, err
+	}
+	if raw.StatusCode != http.StatusAccepted && raw.StatusCode != http.StatusOK {
+		res := new(Response)
+		if json.Unmarshal(data, res) == nil && res.Error() != nil {
+			return raw, res.Error()
+		}
+		return raw, statusError(raw, ErrManifestUnknown)
+	}
+	return raw, nil
+}
+
+// DeleteTag 删除tag指向的manifest，指向同一digest的其他tag会被一并删除，安全检查同DeleteManifest
+func (client *Client) DeleteTag(ctx context.Context, name, tag string, opt *DeleteManifestOption) (*DeleteManifestResult, *http.Response, error) {
+	digest, raw, err := client.GetManifestDigest(ctx, name, tag)
+	if err != nil {
+		return nil, raw, err
+	}
+	return client.DeleteManifest(ctx, name, digest, opt)
+}
+
+// TagsByDigest 查询引用digest的所有tag，包括直接指向该digest的tag，
+// 以及指向manifest list或OCI image index且其中包含该digest的tag。逐个tag通过HEAD请求获取digest
+func (client *Client) TagsByDigest(ctx context.Context, name, digest string) ([]string, error) {
+	refs, err := client.resolveTags(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return refs.tags(digest), nil
+}
+
+// tags 引用digest的tag，按名称排序
+func (refs tagRefs) tags(digest string) []string {
+	var tags []string
+	for tag, ref := range refs {
+		if ref.references(digest) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (ref tagRef) references(digest string) bool {
+	if ref.digest == digest {
+		return true
+	}
+	for _, d := range ref.children {
+		if d == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTags 查询镜像所有tag对应的digest，tag指向manifest list或OCI image index时一并获取其中各平台manifest的digest。
+// 查询期间被删除的tag将被忽略，任一查询失败时不再发起新的查询并返回错误
+func (client *Client) resolveTags(ctx context.Context, name string) (tagRefs, error) {
+	tags, err := client.ListAllTags(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	refs := make(tagRefs, len(tags))
+	indexes := map[string][]string{} // index的digest -> 各平台manifest的digest
+	err = forEach(ctx, tags, func(ctx context.Context, tag string) error {
+		digest, raw, err := client.GetManifestDigest(ctx, name, tag)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		refs[tag] = tagRef{digest: digest}
+		if mediaType := parseMediaType(raw.Header.Get("Content-Type")); mediaType == MediaTypeManifestList || mediaType == MediaTypeOCIIndex {
+			indexes[digest] = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dockerhub: resolve tags of %s: %w", name, err)
+	}
+	if len(indexes) == 0 {
+		return refs, nil
+	}
+
+	// 多个tag指向同一index时只获取一次
+	digests := make([]string, 0, len(indexes))
+	for digest := range indexes {
+		digests = append(digests, digest)
+	}
+	err = forEach(ctx, digests, func(ctx context.Context, digest string) error {
+		manifest, _, err := client.GetManifest(ctx, name, digest)
+		if err != nil {
+			return err
+		}
+		children := make([]string, 0, len(manifest.Manifests))
+		for _, m := range manifest.Manifests {
+			children = append(children, m.Digest)
+		}
+		mu.Lock()
+		indexes[digest] = children
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dockerhub: resolve indexes of %s: %w", name, err)
+	}
+	for tag, ref := range refs {
+		ref.children = indexes[ref.digest]
+		refs[tag] = ref
+	}
+	return refs, nil
+}
+
+// forEach 以resolveConcurrency的并发数对items执行fn，返回ErrManifestUnknown的项被忽略。
+// 首个错误发生后取消其余请求，不再发起新的请求
+func forEach(ctx context.Context, items []string, fn func(ctx context.Context, item string) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	sem := make(chan struct{}, resolveConcurrency)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(item string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := fn(ctx, item)
+			if err == nil || isErrorCode(err, ErrManifestUnknown) {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", item, err)
+				cancel()
+			}
+		}(item)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
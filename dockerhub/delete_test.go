@@ -0,0 +1,112 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_DeleteManifest(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	digest := reg.pushImage("app", "v1", map[string]string{"created": "2021-01-01T00:00:00Z"})
+	reg.pushManifest("app", "latest", MediaTypeOCIManifest, reg.bodies[digest].body)
+	other := reg.pushImage("app", "v2", map[string]string{"created": "2021-02-01T00:00:00Z"})
+
+	tags, err := client.TagsByDigest(ctx, "app", digest)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"latest", "v1"}, tags)
+
+	// 受保护的tag指向该digest时拒绝删除
+	opt := &DeleteManifestOption{ProtectedTags: []string{"latest"}}
+	ret, _, err := client.DeleteManifest(ctx, "app", digest, opt)
+	assert.True(t, errors.Is(err, ErrProtectedTag))
+	assert.Equal(t, []string{"latest", "v1"}, ret.Tags)
+	assert.Equal(t, []string{"latest"}, ret.ProtectedTags)
+	assert.False(t, ret.Deleted)
+	assert.Equal(t, 0, reg.count(http.MethodDelete, "manifests"))
+
+	// DryRun只返回删除计划
+	ret, _, err = client.DeleteManifest(ctx, "app", digest, &DeleteManifestOption{Protected: func(tag string) bool { return tag == "v2" }, DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"latest", "v1"}, ret.Tags)
+	assert.Empty(t, ret.ProtectedTags)
+	assert.False(t, ret.Deleted)
+	assert.Equal(t, 0, reg.count(http.MethodDelete, "manifests"))
+
+	// Force时忽略受保护的tag
+	opt.Force = true
+	ret, _, err = client.DeleteManifest(ctx, "app", digest, opt)
+	assert.Nil(t, err)
+	assert.True(t, ret.Deleted)
+	assert.Equal(t, []string{"v2"}, reg.tags("app"))
+
+	_, _, err = client.DeleteManifest(ctx, "app", "v2", nil)
+	assert.NotNil(t, err)
+	ret, _, err = client.DeleteTag(ctx, "app", "v2", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, other, ret.Digest)
+	assert.Empty(t, reg.tags("app"))
+}
+
+func TestClient_DeleteManifest_Index(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	amd64 := reg.pushImage("app", "", map[string]string{"architecture": "amd64"})
+	arm64 := reg.pushImage("app", "", map[string]string{"architecture": "arm64"})
+	index := reg.pushIndex("app", "stable", amd64, arm64)
+	reg.pushManifest("app", "latest", MediaTypeOCIIndex, reg.bodies[index].body)
+	reg.pushManifest("app", "amd64", MediaTypeOCIManifest, reg.bodies[amd64].body)
+
+	tags, err := client.TagsByDigest(ctx, "app", amd64)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"amd64", "latest", "stable"}, tags)
+
+	// 受保护tag的index引用了该平台manifest时拒绝删除
+	ret, _, err := client.DeleteManifest(ctx, "app", amd64, &DeleteManifestOption{ProtectedTags: []string{"stable"}})
+	assert.True(t, errors.Is(err, ErrProtectedTag))
+	assert.Equal(t, []string{"amd64"}, ret.Tags)
+	assert.Equal(t, []string{"latest", "stable"}, ret.IndexTags)
+	assert.Equal(t, []string{"stable"}, ret.ProtectedTags)
+
+	ret, _, err = client.DeleteManifest(ctx, "app", arm64, &DeleteManifestOption{ProtectedTags: []string{"amd64"}, DryRun: true})
+	assert.Nil(t, err)
+	assert.Empty(t, ret.Tags)
+	assert.Equal(t, []string{"latest", "stable"}, ret.IndexTags)
+
+	ret, _, err = client.DeleteManifest(ctx, "app", index, &DeleteManifestOption{ProtectedTags: []string{"amd64"}})
+	assert.Nil(t, err)
+	assert.True(t, ret.Deleted)
+	assert.Equal(t, []string{"amd64"}, reg.tags("app"))
+	// 每次查询中同一index只获取一次
+	assert.Equal(t, 4, reg.count(http.MethodGet, "manifests"))
+}
+
+func TestClient_TagsByDigest_Error(t *testing.T) {
+	reg, _ := newFakeRegistry(t)
+	for i := 0; i < 100; i++ {
+		reg.pushImage("app", fmt.Sprintf("v%d", i), map[string]int{"i": i})
+	}
+	var heads int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			atomic.AddInt32(&heads, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	client, _ := NewClient(Option{URL: srv.URL})
+
+	// 首个查询失败后不再发起新的查询
+	_, err := client.TagsByDigest(context.Background(), "app", Digest([]byte("any")))
+	assert.NotNil(t, err)
+	assert.Less(t, int(atomic.LoadInt32(&heads)), 2*resolveConcurrency)
+}
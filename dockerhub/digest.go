@@ -0,0 +1,76 @@
+package dockerhub
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// ErrDigestMismatch 内容的摘要与期望的digest不一致
+var ErrDigestMismatch = errors.New("dockerhub: digest mismatch")
+
+// digestVerifier 边写入边计算摘要，用于校验manifest及blob内容
+type digestVerifier struct {
+	expected  string
+	algorithm string
+	hash      hash.Hash
+}
+
+func newDigestVerifier(digest string) (*digestVerifier, error) {
+	i := strings.IndexByte(digest, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("dockerhub: bad digest %q", digest)
+	}
+	v := &digestVerifier{expected: digest, algorithm: digest[:i]}
+	switch v.algorithm {
+	case "sha256":
+		v.hash = sha256.New()
+	case "sha512":
+		v.hash = sha512.New()
+	default:
+		return nil, fmt.Errorf("dockerhub: unsupported digest algorithm %q", v.algorithm)
+	}
+	return v, nil
+}
+
+func (v *digestVerifier) Write(p []byte) (int, error) {
+	return v.hash.Write(p)
+}
+
+// Digest 已写入内容的摘要
+func (v *digestVerifier) Digest() string {
+	return v.algorithm + ":" + hex.EncodeToString(v.hash.Sum(nil))
+}
+
+// Verify 校验已写入内容的摘要
+func (v *digestVerifier) Verify() error {
+	if actual := v.Digest(); actual != v.expected {
+		return fmt.Errorf("%w: expected %s, got %s", ErrDigestMismatch, v.expected, actual)
+	}
+	return nil
+}
+
+// Digest 计算内容的sha256摘要，格式为sha256:<hex>
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// VerifyDigest 校验内容是否与digest一致
+func VerifyDigest(digest string, data []byte) error {
+	v, err := newDigestVerifier(digest)
+	if err != nil {
+		return err
+	}
+	_, _ = v.Write(data)
+	return v.Verify()
+}
+
+// isDigest reference是否为digest而非tag
+func isDigest(reference string) bool {
+	return strings.Contains(reference, ":")
+}
@@ -0,0 +1,97 @@
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+// maxConfigSize 镜像配置blob的大小上限
+const maxConfigSize = 8 << 20
+
+// DefaultPlatform 多平台镜像未指定平台时使用的平台
+var DefaultPlatform = Platform{OS: "linux", Architecture: "amd64"}
+
+// resolveImageManifest 获取单平台的镜像manifest，多平台镜像按platform选择，platform为空时使用DefaultPlatform
+func (client *Client) resolveImageManifest(ctx context.Context, name, reference string, platform *Platform) (*ResponseManifest, error) {
+	if platform == nil {
+		platform = &DefaultPlatform
+	}
+	manifest, _, err := client.GetPlatformManifest(ctx, name, reference, *platform)
+	return manifest, err
+}
+
+// fetchImageConfig 获取镜像配置的原始报文，schema1镜像使用history中最新一层的v1Compatibility
+func (client *Client) fetchImageConfig(ctx context.Context, name string, manifest *ResponseManifest) ([]byte, error) {
+	if manifest.Config == nil {
+		if len(manifest.History) == 0 {
+			return nil, fmt.Errorf("dockerhub: manifest %s has no image config", manifest.MediaType)
+		}
+		return []byte(manifest.History[0].V1Compatibility), nil
+	}
+	rc, _, err := client.GetBlob(ctx, name, manifest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(rc, maxConfigSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxConfigSize {
+		return nil, fmt.Errorf("dockerhub: image config %s exceeds %d bytes", manifest.Config.Digest, maxConfigSize)
+	}
+	return data, nil
+}
+
+// InspectImage 获取镜像的manifest及配置，多平台镜像按platform选择，platform为空时使用DefaultPlatform
+func (client *Client) InspectImage(ctx context.Context, name, reference string, platform *Platform) (*Image, error) {
+	manifest, err := client.resolveImageManifest(ctx, name, reference, platform)
+	if err != nil {
+		return nil, err
+	}
+	config, err := client.getImageConfig(ctx, name, manifest)
+	if err != nil {
+		return nil, err
+	}
+	image := &Image{
+		Digest:      manifest.Digest,
+		MediaType:   manifest.MediaType,
+		Layers:      manifest.Layers,
+		ImageConfig: *config,
+	}
+	for _, layer := range manifest.Layers {
+		image.Size += layer.Size
+	}
+	return image, nil
+}
+
+// Platform 镜像的运行平台
+func (config *ImageConfig) Platform() Platform {
+	return Platform{OS: config.OS, Architecture: config.Architecture, OSVersion: config.OSVersion, Variant: config.Variant}
+}
+
+// Ports 按名称排序的暴露端口，如"8080/tcp"
+func (config *ContainerConfig) Ports() []string {
+	ports := make([]string, 0, len(config.ExposedPorts))
+	for port := range config.ExposedPorts {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+func (client *Client) getImageConfig(ctx context.Context, name string, manifest *ResponseManifest) (*ImageConfig, error) {
+	data, err := client.fetchImageConfig(ctx, name, manifest)
+	if err != nil {
+		return nil, err
+	}
+	config := new(ImageConfig)
+	if err = json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("dockerhub: bad image config: %w", err)
+	}
+	return config, nil
+}
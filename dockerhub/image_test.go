@@ -0,0 +1,67 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_InspectImage(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	created := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	config := func(arch string) map[string]interface{} {
+		return map[string]interface{}{
+			"created":      created,
+			"architecture": arch,
+			"os":           "linux",
+			"config": map[string]interface{}{
+				"ExposedPorts": map[string]struct{}{"8080/tcp": {}, "443/tcp": {}},
+				"Env":          []string{"PATH=/usr/bin"},
+				"Labels":       map[string]string{"version": "1.0"},
+			},
+		}
+	}
+	amd64 := reg.pushImage("app", "amd64", config("amd64"))
+	arm64 := reg.pushImage("app", "", config("arm64"))
+	index := reg.pushIndex("app", "v1", amd64, arm64)
+	// index不含config字段
+	assert.NotContains(t, string(reg.bodies[index].body), `"config"`)
+
+	image, err := client.InspectImage(ctx, "app", "amd64", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, amd64, image.Digest)
+	assert.Equal(t, MediaTypeOCIManifest, image.MediaType)
+	assert.Len(t, image.Layers, 1)
+	assert.Equal(t, image.Layers[0].Size, image.Size)
+	assert.True(t, created.Equal(image.Created))
+	assert.Equal(t, Platform{OS: "linux", Architecture: "amd64"}, image.Platform())
+	assert.Equal(t, []string{"443/tcp", "8080/tcp"}, image.Config.Ports())
+	assert.Equal(t, "1.0", image.Config.Labels["version"])
+
+	// 多平台镜像按platform选择
+	image, err = client.InspectImage(ctx, "app", "v1", &Platform{OS: "linux", Architecture: "arm64"})
+	assert.Nil(t, err)
+	assert.Equal(t, arm64, image.Digest)
+	assert.Equal(t, "arm64", image.Architecture)
+
+	image, err = client.InspectImage(ctx, "app", "v1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, amd64, image.Digest)
+
+	_, err = client.InspectImage(ctx, "app", "v1", &Platform{OS: "windows", Architecture: "amd64"})
+	assert.True(t, errors.Is(err, ErrPlatformNotFound))
+}
+
+func TestClient_InspectImage_ConfigTooLarge(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	reg.pushImage("app", "huge", map[string]string{"padding": strings.Repeat("x", maxConfigSize)})
+
+	_, err := client.InspectImage(context.Background(), "app", "huge", nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "exceeds")
+}
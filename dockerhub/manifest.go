@@ -0,0 +1,162 @@
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/jacexh/requests"
+)
+
+// ErrPlatformNotFound manifest list或OCI image index中没有匹配的平台
+var ErrPlatformNotFound = errors.New("dockerhub: platform not found")
+
+// manifestAccept GetManifest支持的manifest类型，未声明时仓库通常会降级返回schema1
+var manifestAccept = strings.Join([]string{
+	MediaTypeOCIIndex,
+	MediaTypeOCIManifest,
+	MediaTypeManifestList,
+	MediaTypeSchema2Manifest,
+	MediaTypeSchema1SignedManifest,
+	MediaTypeSchema1Manifest,
+}, ", ")
+
+// ParsePlatform 解析os/arch[/variant]格式的平台，如linux/arm64/v8
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("dockerhub: bad platform %q", s)
+	}
+	p := Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// String 返回os/arch[/variant]格式的平台
+func (p Platform) String() string {
+	if p.Variant == "" {
+		return p.OS + "/" + p.Architecture
+	}
+	return p.OS + "/" + p.Architecture + "/" + p.Variant
+}
+
+// Match 判断平台是否满足要求，want未指定variant时不比较variant
+func (p Platform) Match(want Platform) bool {
+	return p.OS == want.OS && p.Architecture == want.Architecture && (want.Variant == "" || p.Variant == want.Variant)
+}
+
+// IsIndex 是否为manifest list或OCI image index
+func (m *ResponseManifest) IsIndex() bool {
+	return m.MediaType == MediaTypeManifestList || m.MediaType == MediaTypeOCIIndex
+}
+
+// SelectPlatform 从manifest list或OCI image index中选择匹配平台的manifest
+func (m *ResponseManifest) SelectPlatform(platform Platform) (*Descriptor, error) {
+	if !m.IsIndex() {
+		return nil, fmt.Errorf("dockerhub: %s is not a manifest list", m.MediaType)
+	}
+	for i := range m.Manifests {
+		if d := &m.Manifests[i]; d.Platform != nil && d.Platform.Match(platform) {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPlatformNotFound, platform)
+}
+
+// GetPlatformManifest 获取指定平台的manifest，reference指向manifest list或OCI image index时自动选择匹配平台的manifest，
+// 指向单平台manifest时直接返回
+func (client *Client) GetPlatformManifest(ctx context.Context, name, reference string, platform Platform) (*ResponseManifest, *http.Response, error) {
+	manifest, raw, err := client.GetManifest(ctx, name, reference)
+	if err != nil || !manifest.IsIndex() {
+		return manifest, raw, err
+	}
+	d, err := manifest.SelectPlatform(platform)
+	if err != nil {
+		return nil, raw, err
+	}
+	return client.GetManifest(ctx, name, d.Digest)
+}
+
+// parseMediaType 去除Content-Type中的参数
+func parseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
+// verify 校验报文摘要：与Docker-Content-Digest一致，按digest获取时还需与reference一致
+func (m *ResponseManifest) verify(reference string) error {
+	if m.MediaType == MediaTypeSchema1SignedManifest {
+		// 签名的schema1 manifest的digest按去除签名后的payload计算，与报文不一致
+		return nil
+	}
+	if m.Digest != "" {
+		if err := VerifyDigest(m.Digest, m.Raw); err != nil {
+			return err
+		}
+	}
+	if isDigest(reference) {
+		if err := VerifyDigest(reference, m.Raw); err != nil {
+			return err
+		}
+		m.Digest = reference
+	}
+	return nil
+}
+
+// PutManifest 推送manifest，reference为tag或digest，返回仓库保存的digest。manifest引用的blob需已上传
+// https://docs.docker.com/registry/spec/api/#pushing-an-image-manifest
+func (client *Client) PutManifest(ctx context.Context, name, reference, mediaType string, body []byte) (string, *http.Response, error) {
+	digest := Digest(body)
+	if isDigest(reference) {
+		if err := VerifyDigest(reference, body); err != nil {
+			return "", nil, err
+		}
+		digest = reference
+	}
+	res := new(Response)
+	raw, data, err := client.do(ctx,
+		http.MethodPut,
+		client.url("/v2/%s/manifests/%s", name, reference),
+		requests.Params{Body: body, Headers: requests.Any{"Content-Type": mediaType}},
+		nil,
+	)
+	if err != nil {
+		return "", raw, err
+	}
+	if raw.StatusCode != http.StatusCreated {
+		if json.Unmarshal(data, res) == nil && res.Error() != nil {
+			return "", raw, res.Error()
+		}
+		if err = statusError(raw, ErrManifestUnknown); err != nil {
+			return "", raw, err
+		}
+		// 仓库未返回201时无法确认manifest已保存
+		return "", raw, fmt.Errorf("dockerhub: unexpected status %s for manifest put", raw.Status)
+	}
+	if stored := raw.Header.Get("Docker-Content-Digest"); stored != "" && stored != digest {
+		return stored, raw, fmt.Errorf("%w: expected %s, registry stored %s", ErrDigestMismatch, digest, stored)
+	}
+	return digest, raw, nil
+}
+
+// Tag 为已有镜像创建新tag：获取srcRef的manifest后原样推送至newTag，不涉及镜像层，digest保持不变
+func (client *Client) Tag(ctx context.Context, name, srcRef, newTag string) (string, *http.Response, error) {
+	manifest, raw, err := client.GetManifest(ctx, name, srcRef)
+	if err != nil {
+		return "", raw, err
+	}
+	if manifest.MediaType == MediaTypeSchema1SignedManifest || manifest.MediaType == MediaTypeSchema1Manifest {
+		// schema1 manifest中包含tag，原样推送会被仓库拒绝
+		return "", raw, fmt.Errorf("dockerhub: cannot tag schema1 manifest %s:%s", name, srcRef)
+	}
+	return client.PutManifest(ctx, name, newTag, manifest.MediaType, manifest.Raw)
+}
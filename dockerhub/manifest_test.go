@@ -0,0 +1,292 @@
+package dockerhub
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlatform(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Platform
+		err  bool
+	}{
+		{in: "linux/amd64", want: Platform{OS: "linux", Architecture: "amd64"}},
+		{in: "linux/arm64/v8", want: Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+		{in: "windows/amd64", want: Platform{OS: "windows", Architecture: "amd64"}},
+		{in: "", err: true},
+		{in: "linux", err: true},
+		{in: "linux/", err: true},
+		{in: "/amd64", err: true},
+		{in: "linux/arm/v7/extra", err: true},
+	}
+	for _, c := range cases {
+		p, err := ParsePlatform(c.in)
+		if c.err {
+			assert.NotNil(t, err, c.in)
+			continue
+		}
+		assert.Nil(t, err, c.in)
+		assert.Equal(t, c.want, p, c.in)
+		assert.Equal(t, c.in, p.String())
+	}
+}
+
+func TestResponseManifest_SelectPlatform(t *testing.T) {
+	index := &ResponseManifest{
+		MediaType: MediaTypeManifestList,
+		Manifests: []Descriptor{
+			{Digest: "sha256:amd64", Platform: &Platform{OS: "linux", Architecture: "amd64"}},
+			{Digest: "sha256:armv6", Platform: &Platform{OS: "linux", Architecture: "arm", Variant: "v6"}},
+			{Digest: "sha256:armv7", Platform: &Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+			{Digest: "sha256:attestation"},
+		},
+	}
+	cases := []struct {
+		platform Platform
+		digest   string
+	}{
+		{platform: Platform{OS: "linux", Architecture: "amd64"}, digest: "sha256:amd64"},
+		{platform: Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, digest: "sha256:armv7"},
+		// 未指定variant时选择第一个匹配os/arch的manifest
+		{platform: Platform{OS: "linux", Architecture: "arm"}, digest: "sha256:armv6"},
+	}
+	for _, c := range cases {
+		d, err := index.SelectPlatform(c.platform)
+		assert.Nil(t, err, c.platform.String())
+		assert.Equal(t, c.digest, d.Digest, c.platform.String())
+	}
+
+	for _, p := range []Platform{{OS: "linux", Architecture: "arm", Variant: "v8"}, {OS: "windows", Architecture: "amd64"}, {}} {
+		_, err := index.SelectPlatform(p)
+		assert.True(t, errors.Is(err, ErrPlatformNotFound), p.String())
+	}
+
+	_, err := (&ResponseManifest{MediaType: MediaTypeOCIManifest}).SelectPlatform(Platform{OS: "linux", Architecture: "amd64"})
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrPlatformNotFound))
+}
+
+func TestClient_GetPlatformManifest(t *testing.T) {
+	reg, _ := newFakeRegistry(t)
+	amd64 := reg.pushImage("app", "", map[string]string{"architecture": "amd64"})
+	arm64 := reg.pushImage("app", "", map[string]string{"architecture": "arm64"})
+	reg.pushIndex("app", "v1", amd64, arm64)
+
+	var mu sync.Mutex
+	var accepts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		accepts = append(accepts, r.Header.Get("Accept"))
+		mu.Unlock()
+		reg.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	client, err := NewClient(Option{URL: srv.URL})
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	manifest, _, err := client.GetPlatformManifest(ctx, "app", "v1", Platform{OS: "linux", Architecture: "arm64"})
+	assert.Nil(t, err)
+	assert.Equal(t, arm64, manifest.Digest)
+	assert.Equal(t, MediaTypeOCIManifest, manifest.MediaType)
+	assert.Len(t, manifest.Layers, 1)
+	assert.Equal(t, 2, reg.count(http.MethodGet, "manifests"))
+
+	// 声明支持OCI及Docker的manifest list和manifest，否则仓库会降级返回schema1
+	assert.Len(t, accepts, 2)
+	for _, accept := range accepts {
+		types := strings.Split(accept, ", ")
+		for _, mediaType := range []string{MediaTypeOCIIndex, MediaTypeOCIManifest, MediaTypeManifestList, MediaTypeSchema2Manifest} {
+			assert.Contains(t, types, mediaType)
+		}
+	}
+
+	// 单平台manifest直接返回
+	manifest, _, err = client.GetPlatformManifest(ctx, "app", amd64, Platform{OS: "linux", Architecture: "arm64"})
+	assert.Nil(t, err)
+	assert.Equal(t, amd64, manifest.Digest)
+	assert.Equal(t, 3, reg.count(http.MethodGet, "manifests"))
+
+	_, _, err = client.GetPlatformManifest(ctx, "app", "v1", Platform{OS: "linux", Architecture: "s390x"})
+	assert.True(t, errors.Is(err, ErrPlatformNotFound))
+}
+
+func TestClient_GetManifest_DigestMismatch(t *testing.T) {
+	body := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json"}`)
+	tampered := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","layers":[]}`)
+	sum := sha512.Sum512(body)
+	sha512Digest := "sha512:" + hex.EncodeToString(sum[:])
+
+	// header为空时不返回Docker-Content-Digest
+	var mu sync.Mutex
+	var payload []byte
+	var header string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", MediaTypeOCIManifest)
+		if header != "" {
+			w.Header().Set("Docker-Content-Digest", header)
+		}
+		_, _ = w.Write(payload)
+	}))
+	defer srv.Close()
+	client, err := NewClient(Option{URL: srv.URL})
+	assert.Nil(t, err)
+	get := func(reference string, data []byte, digest string) (*ResponseManifest, error) {
+		mu.Lock()
+		payload, header = data, digest
+		mu.Unlock()
+		manifest, _, err := client.GetManifest(context.Background(), "app", reference)
+		return manifest, err
+	}
+
+	cases := []struct {
+		name      string
+		reference string
+		body      []byte
+		header    string
+	}{
+		{name: "tag with wrong header", reference: "v1", body: tampered, header: Digest(body)},
+		{name: "digest with wrong header", reference: Digest(tampered), body: tampered, header: Digest(body)},
+		{name: "digest with matching header", reference: Digest(body), body: tampered, header: Digest(body)},
+		{name: "digest without header", reference: Digest(body), body: tampered},
+		{name: "sha512 header", reference: "v1", body: tampered, header: sha512Digest},
+	}
+	for _, c := range cases {
+		_, err = get(c.reference, c.body, c.header)
+		assert.True(t, errors.Is(err, ErrDigestMismatch), c.name)
+	}
+
+	manifest, err := get("v1", body, sha512Digest)
+	assert.Nil(t, err)
+	assert.Equal(t, sha512Digest, manifest.Digest)
+	manifest, err = get(Digest(body), body, "")
+	assert.Nil(t, err)
+	assert.Equal(t, Digest(body), manifest.Digest)
+
+	// 不支持的摘要算法
+	_, err = get("v1", body, "md5:"+strings.Repeat("0", 32))
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrDigestMismatch))
+	assert.Contains(t, err.Error(), "unsupported digest algorithm")
+	_, err = get("sha1:"+strings.Repeat("0", 40), body, "")
+	assert.Contains(t, err.Error(), "unsupported digest algorithm")
+	assert.Contains(t, VerifyDigest("sha384:00", body).Error(), "unsupported digest algorithm")
+}
+
+func TestClient_PutManifest(t *testing.T) {
+	reg, _ := newFakeRegistry(t)
+	digest := reg.pushImage("app", "v1", map[string]string{"architecture": "amd64"})
+	body := reg.bodies[digest].body
+
+	// status及stored为空时交由fakeRegistry处理
+	var mu sync.Mutex
+	var status int
+	var stored, errBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		s, d, e := status, stored, errBody
+		mu.Unlock()
+		if r.Method != http.MethodPut || (s == 0 && d == "") {
+			reg.ServeHTTP(w, r)
+			return
+		}
+		if s == 0 {
+			s = http.StatusCreated
+		}
+		if d != "" {
+			w.Header().Set("Docker-Content-Digest", d)
+		}
+		w.WriteHeader(s)
+		_, _ = w.Write([]byte(e))
+	}))
+	defer srv.Close()
+	client, err := NewClient(Option{URL: srv.URL})
+	assert.Nil(t, err)
+	ctx := context.Background()
+	respond := func(s int, d, e string) {
+		mu.Lock()
+		status, stored, errBody = s, d, e
+		mu.Unlock()
+	}
+
+	ret, raw, err := client.PutManifest(ctx, "app", "v2", MediaTypeOCIManifest, body)
+	assert.Nil(t, err)
+	assert.Equal(t, digest, ret)
+	assert.Equal(t, http.StatusCreated, raw.StatusCode)
+	assert.Equal(t, []string{"v1", "v2"}, reg.tags("app"))
+
+	// 按digest推送时先校验报文
+	_, raw, err = client.PutManifest(ctx, "app", Digest([]byte("other")), MediaTypeOCIManifest, body)
+	assert.True(t, errors.Is(err, ErrDigestMismatch))
+	assert.Nil(t, raw)
+	assert.Equal(t, 1, reg.count(http.MethodPut, "manifests"))
+
+	// 仓库保存的digest与报文不一致
+	respond(http.StatusCreated, Digest([]byte("other")), "")
+	ret, _, err = client.PutManifest(ctx, "app", "v3", MediaTypeOCIManifest, body)
+	assert.True(t, errors.Is(err, ErrDigestMismatch))
+	assert.Equal(t, Digest([]byte("other")), ret)
+
+	// 非201均视为失败
+	respond(http.StatusOK, digest, "")
+	ret, _, err = client.PutManifest(ctx, "app", "v3", MediaTypeOCIManifest, body)
+	assert.NotNil(t, err)
+	assert.Empty(t, ret)
+	assert.Contains(t, err.Error(), "unexpected status")
+
+	// 4xx时解析错误报文
+	respond(http.StatusBadRequest, "", `{"errors":[{"code":"MANIFEST_BLOB_UNKNOWN","message":"blob unknown to registry","detail":"sha256:abc"}]}`)
+	_, raw, err = client.PutManifest(ctx, "app", "v3", MediaTypeOCIManifest, body)
+	assert.True(t, isErrorCode(err, ErrManifestBlobUnknown))
+	assert.Equal(t, http.StatusBadRequest, raw.StatusCode)
+	assert.Contains(t, err.Error(), "blob unknown to registry")
+
+	respond(http.StatusForbidden, "", "forbidden")
+	_, _, err = client.PutManifest(ctx, "app", "v3", MediaTypeOCIManifest, body)
+	assert.True(t, isErrorCode(err, ErrDenied))
+	assert.Equal(t, []string{"v1", "v2"}, reg.tags("app"))
+}
+
+func TestClient_Tag(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	amd64 := reg.pushImage("app", "", map[string]string{"architecture": "amd64"})
+	arm64 := reg.pushImage("app", "", map[string]string{"architecture": "arm64"})
+	index := reg.pushIndex("app", "v1", amd64, arm64)
+
+	// index原样推送，digest不变
+	digest, _, err := client.Tag(ctx, "app", "v1", "stable")
+	assert.Nil(t, err)
+	assert.Equal(t, index, digest)
+	assert.Equal(t, MediaTypeOCIIndex, reg.bodies[index].mediaType)
+	assert.Equal(t, []string{"stable", "v1"}, reg.tags("app"))
+
+	digest, _, err = client.Tag(ctx, "app", amd64, "amd64")
+	assert.Nil(t, err)
+	assert.Equal(t, amd64, digest)
+
+	// schema1 manifest中包含tag，拒绝推送
+	for _, mediaType := range []string{MediaTypeSchema1Manifest, MediaTypeSchema1SignedManifest} {
+		reg.pushManifest("legacy", "v1", mediaType, []byte(`{"schemaVersion":1,"name":"legacy","tag":"v1"}`))
+		_, _, err = client.Tag(ctx, "legacy", "v1", "stable")
+		assert.NotNil(t, err, mediaType)
+		assert.Contains(t, err.Error(), "schema1", mediaType)
+	}
+	assert.Equal(t, []string{"v1"}, reg.tags("legacy"))
+	assert.Equal(t, 2, reg.count(http.MethodPut, "manifests"))
+
+	_, _, err = client.Tag(ctx, "app", "missing", "stable")
+	assert.True(t, isErrorCode(err, ErrManifestUnknown))
+}
@@ -1,8 +1,11 @@
 package dockerhub
 
+import "net/http"
+
 type Option struct {
-	URL       string // 为空时表示使用官方仓库
-	AuthToken string // 有则优先使用
-	Username  string // 用户名
-	Password  string // 密码
+	URL        string       // 为空时表示使用官方仓库
+	AuthToken  string       // 有则优先使用
+	Username   string       // 用户名
+	Password   string       // 密码
+	HTTPClient *http.Client // 发送请求使用的http.Client，为空时使用默认配置，可用于注入cassette.Recorder等自定义Transport
 }
@@ -0,0 +1,167 @@
+package dockerhub
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jacexh/requests"
+)
+
+type (
+	// Pager 按Link header逐页遍历tag或存储库：
+	//
+	//	pager := client.TagPager("library/nginx", &ListTagsOption{Number: 100})
+	//	for pager.HasNext() {
+	//		tags, _, err := pager.Next(ctx)
+	//	}
+	Pager struct {
+		client  *Client
+		next    string
+		query   requests.Any
+		started bool
+		fetch   func(*Response) []string
+	}
+)
+
+func (opt *ListTagsOption) query() requests.Any {
+	if opt == nil {
+		return nil
+	}
+	return pageQuery(opt.Number, opt.Last)
+}
+
+func (opt *ListRepositoriesOption) query() requests.Any {
+	if opt == nil {
+		return nil
+	}
+	return pageQuery(opt.Number, opt.Last)
+}
+
+func pageQuery(n int, last string) requests.Any {
+	query := requests.Any{}
+	if n > 0 {
+		query["n"] = strconv.Itoa(n)
+	}
+	if last != "" {
+		query["last"] = last
+	}
+	return query
+}
+
+// TagPager 返回遍历镜像所有tag的Pager
+func (client *Client) TagPager(name string, opt *ListTagsOption) *Pager {
+	return &Pager{
+		client: client,
+		next:   client.url("/v2/%s/tags/list", name),
+		query:  opt.query(),
+		fetch: func(res *Response) []string {
+			if res.ResponseTag == nil {
+				return nil
+			}
+			return res.Tags
+		},
+	}
+}
+
+// RepositoryPager 返回遍历所有存储库的Pager
+func (client *Client) RepositoryPager(opt *ListRepositoriesOption) *Pager {
+	return &Pager{
+		client: client,
+		next:   client.url("/v2/_catalog"),
+		query:  opt.query(),
+		fetch: func(res *Response) []string {
+			if res.ResponseRepository == nil {
+				return nil
+			}
+			return res.Repository
+		},
+	}
+}
+
+// HasNext 是否还有下一页
+func (p *Pager) HasNext() bool {
+	return p.next != ""
+}
+
+// Next 获取下一页，没有下一页时返回空
+func (p *Pager) Next(ctx context.Context) ([]string, *http.Response, error) {
+	if p.next == "" {
+		return nil, nil, nil
+	}
+	var query requests.Any
+	if !p.started {
+		// 后续页的查询参数已包含在Link中
+		query = p.query
+	}
+	res, raw, next, err := p.client.list(ctx, p.next, query)
+	if err != nil {
+		return nil, raw, err
+	}
+	p.started = true
+	p.next = next
+	return p.fetch(res), raw, nil
+}
+
+// All 获取剩余所有页
+func (p *Pager) All(ctx context.Context) ([]string, error) {
+	var all []string
+	for p.HasNext() {
+		items, _, err := p.Next(ctx)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+// ListAllTags 查询镜像的所有tag，pageSize为每页数量
+func (client *Client) ListAllTags(ctx context.Context, name string, pageSize int) ([]string, error) {
+	return client.TagPager(name, &ListTagsOption{Number: pageSize}).All(ctx)
+}
+
+// ListAllRepositories 查询仓库中的所有存储库，pageSize为每页数量
+func (client *Client) ListAllRepositories(ctx context.Context, pageSize int) ([]string, error) {
+	return client.RepositoryPager(&ListRepositoriesOption{Number: pageSize}).All(ctx)
+}
+
+// list 查询一页数据，返回下一页的地址
+func (client *Client) list(ctx context.Context, path string, query requests.Any) (*Response, *http.Response, string, error) {
+	res := new(Response)
+	raw, _, err := client.do(ctx, http.MethodGet, path, requests.Params{Query: query}, requests.UnmarshalJSONResponse(res))
+	if err != nil {
+		return nil, raw, "", err
+	}
+	if res.Error() != nil {
+		return nil, raw, "", res.Error()
+	}
+	return res, raw, nextLink(raw), nil
+}
+
+// nextLink 解析Link header中rel="next"的地址，如</v2/_catalog?last=b&n=2>; rel="next"，相对地址基于请求地址补全
+func nextLink(res *http.Response) string {
+	for _, header := range res.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				param = strings.ReplaceAll(strings.TrimSpace(param), " ", "")
+				if param != `rel="next"` && param != "rel=next" {
+					continue
+				}
+				u, err := url.Parse(target[1 : len(target)-1])
+				if err != nil {
+					return ""
+				}
+				return res.Request.URL.ResolveReference(u).String()
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package dockerhub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextLink(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://registry.example.com/v2/app/tags/list?n=2", nil)
+	cases := map[string]string{
+		`</v2/app/tags/list?n=2&last=b>; rel="next"`:                                     "https://registry.example.com/v2/app/tags/list?n=2&last=b",
+		`<https://cdn.example.com/v2/app/tags/list?last=b>; rel=next`:                    "https://cdn.example.com/v2/app/tags/list?last=b",
+		`</v2/app/tags/list?last=a>; rel="prev", </v2/app/tags/list?last=c>; rel="next"`: "https://registry.example.com/v2/app/tags/list?last=c",
+		`</v2/app/tags/list?last=a>; rel="prev"`:                                         "",
+		`/v2/app/tags/list?last=a; rel="next"`:                                           "",
+		`</v2/app/tags/list?last=b>;   rel = "next"`:                                     "https://registry.example.com/v2/app/tags/list?last=b",
+	}
+	for link, want := range cases {
+		res := &http.Response{Header: http.Header{"Link": {link}}, Request: req}
+		assert.Equal(t, want, nextLink(res), link)
+	}
+	assert.Equal(t, "", nextLink(&http.Response{Header: http.Header{}, Request: req}))
+}
+
+func TestClient_ListTags(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		reg.pushImage("app", fmt.Sprintf("v%d", i), map[string]int{"i": i})
+	}
+
+	tags, _, err := client.ListTags(ctx, "app", &ListTagsOption{Number: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v0", "v1"}, tags.Tags)
+	next, _ := url.Parse(tags.Next)
+	assert.Equal(t, "v1", next.Query().Get("last"))
+
+	tags, _, err = client.ListTags(ctx, "app", &ListTagsOption{Number: 2, Last: "v3"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v4"}, tags.Tags)
+	assert.Empty(t, tags.Next)
+
+	pager := client.TagPager("app", &ListTagsOption{Number: 2})
+	var pages [][]string
+	for pager.HasNext() {
+		page, _, err := pager.Next(ctx)
+		assert.Nil(t, err)
+		pages = append(pages, page)
+	}
+	assert.Equal(t, [][]string{{"v0", "v1"}, {"v2", "v3"}, {"v4"}}, pages)
+
+	all, err := client.ListAllTags(ctx, "app", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v0", "v1", "v2", "v3", "v4"}, all)
+	assert.Equal(t, 2+3+2, reg.count(http.MethodGet, "tags"))
+}
+
+func TestClient_ListRepositories(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	for _, name := range []string{"library/nginx", "app", "team/api"} {
+		reg.pushImage(name, "latest", map[string]string{})
+	}
+
+	repos, _, err := client.ListRepositories(context.Background(), &ListRepositoriesOption{Number: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app", "library/nginx"}, repos.Repository)
+	assert.NotEmpty(t, repos.Next)
+
+	all, err := client.ListAllRepositories(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app", "library/nginx", "team/api"}, all)
+}
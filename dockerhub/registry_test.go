@@ -0,0 +1,344 @@
+package dockerhub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type (
+	// fakeRegistry 实现distribution api中manifest、tag、catalog及blob上传下载的内存仓库
+	fakeRegistry struct {
+		mu        sync.Mutex
+		manifests map[string]map[string]string // 存储库 -> tag或digest -> digest
+		bodies    map[string]fakeManifest      // digest -> manifest
+		blobs     map[string][]byte
+		uploads   map[string]*bytes.Buffer
+		seq       int
+		requests  map[string]int // "METHOD 类型"的请求次数，类型为manifests、blobs、uploads、tags、catalog
+
+		cuts    int // 之后的cuts次blob下载在发送cutAt字节后断开连接
+		cutAt   int
+		noRange bool
+	}
+
+	fakeManifest struct {
+		mediaType string
+		body      []byte
+	}
+)
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *Client) {
+	reg := &fakeRegistry{
+		manifests: map[string]map[string]string{},
+		bodies:    map[string]fakeManifest{},
+		blobs:     map[string][]byte{},
+		uploads:   map[string]*bytes.Buffer{},
+		requests:  map[string]int{},
+	}
+	srv := httptest.NewServer(reg)
+	t.Cleanup(srv.Close)
+	client, err := NewClient(Option{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg, client
+}
+
+func (reg *fakeRegistry) count(method, kind string) int {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.requests[method+" "+kind]
+}
+
+// pushBlob 直接写入blob，返回digest
+func (reg *fakeRegistry) pushBlob(data []byte) string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	digest := Digest(data)
+	reg.blobs[digest] = data
+	return digest
+}
+
+// pushManifest 直接写入manifest，reference为空时仅能通过digest访问
+func (reg *fakeRegistry) pushManifest(name, reference, mediaType string, body []byte) string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	digest := Digest(body)
+	reg.bodies[digest] = fakeManifest{mediaType: mediaType, body: body}
+	if reg.manifests[name] == nil {
+		reg.manifests[name] = map[string]string{}
+	}
+	reg.manifests[name][digest] = digest
+	if reference != "" {
+		reg.manifests[name][reference] = digest
+	}
+	return digest
+}
+
+// pushImage 写入镜像配置、一个镜像层及OCI image manifest，返回manifest的digest
+func (reg *fakeRegistry) pushImage(name, tag string, config interface{}) string {
+	data, _ := json.Marshal(config)
+	layer := []byte("layer of " + string(data))
+	manifest, _ := json.Marshal(ResponseManifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeOCIManifest,
+		Config:        &Descriptor{MediaType: MediaTypeOCIConfig, Digest: reg.pushBlob(data), Size: int64(len(data))},
+		Layers:        []Descriptor{{MediaType: MediaTypeOCILayer, Digest: reg.pushBlob(layer), Size: int64(len(layer))}},
+	})
+	return reg.pushManifest(name, tag, MediaTypeOCIManifest, manifest)
+}
+
+// pushIndex 写入引用manifests的OCI image index，平台依次为linux/amd64、linux/arm64
+func (reg *fakeRegistry) pushIndex(name, tag string, manifests ...string) string {
+	platforms := []Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64"}}
+	index := ResponseManifest{SchemaVersion: 2, MediaType: MediaTypeOCIIndex}
+	for i, digest := range manifests {
+		platform := platforms[i]
+		reg.mu.Lock()
+		size := len(reg.bodies[digest].body)
+		reg.mu.Unlock()
+		index.Manifests = append(index.Manifests, Descriptor{MediaType: MediaTypeOCIManifest, Digest: digest, Size: int64(size), Platform: &platform})
+	}
+	body, _ := json.Marshal(index)
+	return reg.pushManifest(name, tag, MediaTypeOCIIndex, body)
+}
+
+func (reg *fakeRegistry) tags(name string) []string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	var tags []string
+	for ref := range reg.manifests[name] {
+		if !isDigest(ref) {
+			tags = append(tags, ref)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (reg *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case path == "_catalog":
+		reg.record(r, "catalog")
+		reg.mu.Lock()
+		var names []string
+		for name := range reg.manifests {
+			names = append(names, name)
+		}
+		reg.mu.Unlock()
+		sort.Strings(names)
+		reg.page(w, r, "repositories", names)
+	case strings.HasSuffix(path, "/tags/list"):
+		reg.record(r, "tags")
+		reg.page(w, r, "tags", reg.tags(strings.TrimSuffix(path, "/tags/list")))
+	case strings.Contains(path, "/manifests/"):
+		reg.record(r, "manifests")
+		i := strings.Index(path, "/manifests/")
+		reg.serveManifest(w, r, path[:i], path[i+len("/manifests/"):])
+	case strings.Contains(path, "/blobs/uploads/"):
+		reg.record(r, "uploads")
+		i := strings.Index(path, "/blobs/uploads/")
+		reg.serveUpload(w, r, path[:i], path[i+len("/blobs/uploads/"):])
+	case strings.Contains(path, "/blobs/"):
+		reg.record(r, "blobs")
+		reg.serveBlob(w, r, path[strings.LastIndex(path, "/")+1:])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (reg *fakeRegistry) record(r *http.Request, kind string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.requests[r.Method+" "+kind]++
+}
+
+func writeError(w http.ResponseWriter, status int, code ErrorCode) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Response{Errors: []Error{{Code: code, Message: string(code)}}})
+}
+
+// page 按n及last分页，还有下一页时返回Link header
+func (reg *fakeRegistry) page(w http.ResponseWriter, r *http.Request, key string, items []string) {
+	query := r.URL.Query()
+	if last := query.Get("last"); last != "" {
+		i := sort.SearchStrings(items, last)
+		if i < len(items) && items[i] == last {
+			i++
+		}
+		items = items[i:]
+	}
+	if n, _ := strconv.Atoi(query.Get("n")); n > 0 && len(items) > n {
+		items = items[:n]
+		next := url.Values{"n": {strconv.Itoa(n)}, "last": {items[n-1]}}
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{key: items})
+}
+
+func (reg *fakeRegistry) serveManifest(w http.ResponseWriter, r *http.Request, name, reference string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	refs := reg.manifests[name]
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		digest := Digest(body)
+		if refs == nil {
+			refs = map[string]string{}
+			reg.manifests[name] = refs
+		}
+		reg.bodies[digest] = fakeManifest{mediaType: r.Header.Get("Content-Type"), body: body}
+		refs[reference], refs[digest] = digest, digest
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		digest, ok := refs[reference]
+		if !ok || !isDigest(reference) {
+			writeError(w, http.StatusNotFound, ErrManifestUnknown)
+			return
+		}
+		for ref, d := range refs {
+			if d == digest {
+				delete(refs, ref)
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		digest, ok := refs[reference]
+		if !ok {
+			writeError(w, http.StatusNotFound, ErrManifestUnknown)
+			return
+		}
+		manifest := reg.bodies[digest]
+		w.Header().Set("Content-Type", manifest.mediaType)
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Header().Set("Content-Length", strconv.Itoa(len(manifest.body)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(manifest.body)
+		}
+	}
+}
+
+func (reg *fakeRegistry) serveBlob(w http.ResponseWriter, r *http.Request, digest string) {
+	reg.mu.Lock()
+	data, ok := reg.blobs[digest]
+	cut := r.Method == http.MethodGet && reg.cuts > 0
+	if cut {
+		reg.cuts--
+	}
+	cutAt, noRange := reg.cutAt, reg.noRange
+	reg.mu.Unlock()
+	if !ok {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeError(w, http.StatusNotFound, ErrBlobUnknown)
+		return
+	}
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	status := http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" && !noRange {
+		offset, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(data)-1, len(data)))
+		status, data = http.StatusPartialContent, data[offset:]
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if cut && cutAt < len(data) {
+		// 模拟传输中断
+		_, _ = w.Write(data[:cutAt])
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		_ = conn.Close()
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+func (reg *fakeRegistry) serveUpload(w http.ResponseWriter, r *http.Request, name, id string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if id == "" && r.Method == http.MethodPost {
+		query := r.URL.Query()
+		if mount := query.Get("mount"); mount != "" {
+			if _, ok := reg.manifests[query.Get("from")]; ok && reg.blobs[mount] != nil {
+				w.Header().Set("Docker-Content-Digest", mount)
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+		}
+		reg.seq++
+		id = strconv.Itoa(reg.seq)
+		reg.uploads[id] = new(bytes.Buffer)
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s?_state=%d", name, id, reg.seq))
+		w.Header().Set("Docker-Upload-UUID", id)
+		w.Header().Set("Range", "0-0")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	buf, ok := reg.uploads[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrBlobUploadUnknown)
+		return
+	}
+	location := fmt.Sprintf("/v2/%s/blobs/uploads/%s?_state=%d", name, id, buf.Len())
+	w.Header().Set("Docker-Upload-UUID", id)
+	switch r.Method {
+	case http.MethodPatch:
+		if rng := r.Header.Get("Content-Range"); rng != "" && !strings.HasPrefix(rng, strconv.Itoa(buf.Len())+"-") {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		buf.Write(data)
+		w.Header().Set("Location", location)
+		w.Header().Set("Range", fmt.Sprintf("0-%d", buf.Len()-1))
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodGet:
+		w.Header().Set("Location", location)
+		w.Header().Set("Range", fmt.Sprintf("0-%d", maxInt(buf.Len()-1, 0)))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		buf.Write(data)
+		digest := r.URL.Query().Get("digest")
+		if Digest(buf.Bytes()) != digest {
+			writeError(w, http.StatusBadRequest, ErrDigestInvalid)
+			return
+		}
+		reg.blobs[digest] = buf.Bytes()
+		delete(reg.uploads, id)
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		delete(reg.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
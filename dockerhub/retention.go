@@ -0,0 +1,309 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SemverPattern 正式发布的语义化版本号，如1.2.3、v1.2.3，不含预发布版本
+var SemverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)$`)
+
+// RetentionDecision.Reason的取值
+const (
+	ReasonKeepLast     = "keep-last"
+	ReasonKeepPattern  = "keep-pattern"
+	ReasonKeepSemver   = "keep-semver"
+	ReasonWithinMaxAge = "within-max-age"
+	ReasonUnknownAge   = "unknown-creation-time"
+	ReasonSharedDigest = "shared-digest" // 与保留的tag指向同一digest，删除会一并删除保留的tag
+	ReasonDelete       = "delete"
+)
+
+// defaultApplyConcurrency 执行清理计划时的默认并发删除数
+const defaultApplyConcurrency = 4
+
+type (
+	// RetentionPolicy tag保留策略，满足任一保留规则的tag保留，其余tag删除；
+	// 设置MaxAge时仅删除创建时间早于MaxAge的tag
+	RetentionPolicy struct {
+		KeepLast     int              // 按镜像配置中的创建时间保留最新的N个镜像（digest），指向这些镜像的tag均保留
+		KeepPatterns []*regexp.Regexp // 保留名称匹配的tag
+		KeepSemver   bool             // 保留正式发布的语义化版本号tag
+		MaxAge       time.Duration    // 为0时不限制
+		Platform     *Platform        // 多平台镜像读取创建时间使用的平台，为空时使用DefaultPlatform
+	}
+
+	// RetentionDecision 单个tag的处理结果
+	RetentionDecision struct {
+		Tag     string
+		Digest  string
+		Created time.Time // 为零值时创建时间未知，镜像配置中未记录或为1970-01-01（可复现构建）
+		Keep    bool
+		Reason  string
+	}
+
+	// RetentionPlan 清理计划，Delete为待删除的manifest digest及其tag
+	RetentionPlan struct {
+		Name      string
+		Policy    RetentionPolicy
+		Decisions []*RetentionDecision
+		Delete    map[string][]string
+	}
+
+	// RetentionResult 单个manifest的删除结果
+	RetentionResult struct {
+		Digest  string
+		Tags    []string
+		Skipped bool // 计划生成后引用该digest的tag发生了变化，未删除，Err为ErrTagsChanged
+		Err     error
+	}
+
+	// RetentionReport 清理计划的执行报告
+	RetentionReport struct {
+		Name    string
+		Results []*RetentionResult
+	}
+)
+
+// PlanRetention 按策略生成镜像的清理计划，不执行删除
+func (client *Client) PlanRetention(ctx context.Context, name string, policy RetentionPolicy) (*RetentionPlan, error) {
+	tags, err := client.ListAllTags(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	decisions, err := client.inspectTags(ctx, name, tags, policy.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按创建时间由新到旧排序，创建时间相同时按tag排序保证结果稳定
+	sort.Slice(decisions, func(i, j int) bool {
+		if !decisions[i].Created.Equal(decisions[j].Created) {
+			return decisions[i].Created.After(decisions[j].Created)
+		}
+		return decisions[i].Tag < decisions[j].Tag
+	})
+	// 指向同一digest的tag为同一镜像，按digest排名
+	ranks := map[string]int{}
+	now := time.Now()
+	for _, d := range decisions {
+		rank, ok := ranks[d.Digest]
+		if !ok {
+			rank = len(ranks)
+			ranks[d.Digest] = rank
+		}
+		d.Keep, d.Reason = policy.evaluate(d, rank, now)
+	}
+
+	// 同一digest的tag只能一起删除，其中有保留的tag时整体保留
+	kept := map[string]bool{}
+	for _, d := range decisions {
+		if d.Keep {
+			kept[d.Digest] = true
+		}
+	}
+	plan := &RetentionPlan{Name: name, Policy: policy, Decisions: decisions, Delete: map[string][]string{}}
+	for _, d := range decisions {
+		if d.Keep {
+			continue
+		}
+		if kept[d.Digest] {
+			d.Keep, d.Reason = true, ReasonSharedDigest
+			continue
+		}
+		plan.Delete[d.Digest] = append(plan.Delete[d.Digest], d.Tag)
+	}
+	return plan, nil
+}
+
+// ApplyRetention 执行清理计划，concurrency为并发删除数，小于等于0时使用默认值。
+// 删除前重新查询一次所有tag的引用，引用digest的tag与计划不一致时跳过该digest；删除时按策略的保留规则检查受保护的tag
+func (client *Client) ApplyRetention(ctx context.Context, plan *RetentionPlan, concurrency int) *RetentionReport {
+	if concurrency <= 0 {
+		concurrency = defaultApplyConcurrency
+	}
+	digests := make([]string, 0, len(plan.Delete))
+	for digest := range plan.Delete {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+
+	report := &RetentionReport{Name: plan.Name, Results: make([]*RetentionResult, len(digests))}
+	refs, err := client.resolveTags(ctx, plan.Name)
+	if err != nil {
+		for i, digest := range digests {
+			report.Results[i] = &RetentionResult{Digest: digest, Tags: plan.Delete[digest], Err: err}
+		}
+		return report
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, digest := range digests {
+		result := &RetentionResult{Digest: digest, Tags: plan.Delete[digest]}
+		report.Results[i] = result
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			_, _, result.Err = client.deleteWithRefs(ctx, plan.Name, result.Digest, refs, &DeleteManifestOption{
+				Protected:    plan.Policy.protects,
+				ExpectedTags: result.Tags,
+			})
+			result.Skipped = errors.Is(result.Err, ErrTagsChanged)
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// Kept 保留的tag
+func (plan *RetentionPlan) Kept() []string {
+	var tags []string
+	for _, d := range plan.Decisions {
+		if d.Keep {
+			tags = append(tags, d.Tag)
+		}
+	}
+	return tags
+}
+
+// Deleted 删除成功的tag数量
+func (report *RetentionReport) Deleted() int {
+	n := 0
+	for _, r := range report.Results {
+		if r.Err == nil {
+			n += len(r.Tags)
+		}
+	}
+	return n
+}
+
+// Failed 删除失败的manifest，不含跳过的manifest
+func (report *RetentionReport) Failed() []*RetentionResult {
+	var failed []*RetentionResult
+	for _, r := range report.Results {
+		if r.Err != nil && !r.Skipped {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Skipped 因tag发生变化而跳过的manifest
+func (report *RetentionReport) Skipped() []*RetentionResult {
+	var skipped []*RetentionResult
+	for _, r := range report.Results {
+		if r.Skipped {
+			skipped = append(skipped, r)
+		}
+	}
+	return skipped
+}
+
+// String 报告摘要
+func (report *RetentionReport) String() string {
+	failed, skipped := len(report.Failed()), len(report.Skipped())
+	return fmt.Sprintf("%s: %d manifests, %d tags deleted, %d skipped, %d failed", report.Name, len(report.Results)-failed-skipped, report.Deleted(), skipped, failed)
+}
+
+// evaluate rank为digest按创建时间由新到旧的序号
+func (policy RetentionPolicy) evaluate(d *RetentionDecision, rank int, now time.Time) (bool, string) {
+	if rank < policy.KeepLast {
+		return true, ReasonKeepLast
+	}
+	if reason := policy.keepReason(d.Tag); reason != "" {
+		return true, reason
+	}
+	if d.Created.IsZero() {
+		return true, ReasonUnknownAge
+	}
+	if policy.MaxAge > 0 && now.Sub(d.Created) < policy.MaxAge {
+		return true, ReasonWithinMaxAge
+	}
+	return false, ReasonDelete
+}
+
+// keepReason 按名称保留tag的规则，不保留时返回空字符串
+func (policy RetentionPolicy) keepReason(tag string) string {
+	if policy.KeepSemver && SemverPattern.MatchString(tag) {
+		return ReasonKeepSemver
+	}
+	for _, pattern := range policy.KeepPatterns {
+		if pattern.MatchString(tag) {
+			return ReasonKeepPattern
+		}
+	}
+	return ""
+}
+
+// protects 执行清理计划时受保护的tag
+func (policy RetentionPolicy) protects(tag string) bool {
+	return policy.keepReason(tag) != ""
+}
+
+// inspectTags 并发查询tag的digest及创建时间，同一digest的镜像配置只获取一次。
+// 查询期间被删除的tag将被忽略，任一查询失败时不再发起新的查询并返回错误
+func (client *Client) inspectTags(ctx context.Context, name string, tags []string, platform *Platform) ([]*RetentionDecision, error) {
+	var mu sync.Mutex
+	created := map[string]time.Time{}
+	decisions := make([]*RetentionDecision, 0, len(tags))
+	err := forEach(ctx, tags, func(ctx context.Context, tag string) error {
+		d, err := client.inspectTag(ctx, name, tag, platform, &mu, created)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		decisions = append(decisions, d)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dockerhub: inspect tags of %s: %w", name, err)
+	}
+	return decisions, nil
+}
+
+func (client *Client) inspectTag(ctx context.Context, name, tag string, platform *Platform, mu *sync.Mutex, created map[string]time.Time) (*RetentionDecision, error) {
+	// 删除按tag直接指向的digest进行，多平台镜像即为index的digest
+	digest, _, err := client.GetManifestDigest(ctx, name, tag)
+	if err != nil {
+		return nil, err
+	}
+	d := &RetentionDecision{Tag: tag, Digest: digest}
+	mu.Lock()
+	t, ok := created[digest]
+	mu.Unlock()
+	if ok {
+		d.Created = t
+		return d, nil
+	}
+
+	manifest, err := client.resolveImageManifest(ctx, name, digest, platform)
+	if err != nil {
+		return nil, err
+	}
+	config, err := client.getImageConfig(ctx, name, manifest)
+	if err != nil {
+		return nil, err
+	}
+	// 可复现构建（如SOURCE_DATE_EPOCH=0）将创建时间固定为1970-01-01，视为未知
+	if config.Created.Unix() != 0 {
+		d.Created = config.Created
+	}
+	mu.Lock()
+	created[digest] = d.Created
+	mu.Unlock()
+	return d, nil
+}
@@ -0,0 +1,168 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pushRetentionImages 写入用于测试保留策略的镜像，返回tag -> digest
+func pushRetentionImages(reg *fakeRegistry) map[string]string {
+	now := time.Now().UTC()
+	image := func(tag string, created interface{}) string {
+		config := map[string]interface{}{"architecture": "amd64", "os": "linux", "tag": tag}
+		if created != nil {
+			config["created"] = created
+		}
+		return reg.pushImage("app", tag, config)
+	}
+	alias := func(tag, digest string) string {
+		return reg.pushManifest("app", tag, MediaTypeOCIManifest, reg.bodies[digest].body)
+	}
+
+	digests := map[string]string{
+		"main":      image("main", now.Add(-time.Hour)),
+		"feature":   image("feature", now.Add(-48*time.Hour)),
+		"old":       image("old", now.AddDate(-1, 0, 0)),
+		"shared":    image("shared", now.AddDate(-1, -1, 0)),
+		"v1.0.0":    image("v1.0.0", now.AddDate(-2, 0, 0)),
+		"rc":        image("rc", now.AddDate(-2, 0, 0)),
+		"epoch":     image("epoch", time.Unix(0, 0).UTC()),
+		"undefined": image("undefined", nil),
+	}
+	digests["latest"] = alias("latest", digests["main"])
+	digests["keep-shared"] = alias("keep-shared", digests["shared"])
+	return digests
+}
+
+func TestClient_PlanRetention(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	digests := pushRetentionImages(reg)
+	policy := RetentionPolicy{
+		KeepLast:     2,
+		KeepPatterns: []*regexp.Regexp{regexp.MustCompile(`^keep-`)},
+		KeepSemver:   true,
+		MaxAge:       24 * time.Hour,
+	}
+
+	plan, err := client.PlanRetention(context.Background(), "app", policy)
+	assert.Nil(t, err)
+	assert.Equal(t, policy, plan.Policy)
+
+	reasons := map[string]string{}
+	for _, d := range plan.Decisions {
+		reasons[d.Tag] = d.Reason
+		assert.Equal(t, digests[d.Tag], d.Digest)
+		assert.Equal(t, d.Reason != ReasonDelete, d.Keep)
+	}
+	assert.Equal(t, map[string]string{
+		// latest与main为同一镜像，KeepLast按digest计数
+		"latest":  ReasonKeepLast,
+		"main":    ReasonKeepLast,
+		"feature": ReasonKeepLast,
+		"old":     ReasonDelete,
+		// 与保留的tag指向同一digest
+		"shared":      ReasonSharedDigest,
+		"keep-shared": ReasonKeepPattern,
+		"v1.0.0":      ReasonKeepSemver,
+		"rc":          ReasonDelete,
+		// 1970-01-01与未记录创建时间均视为未知
+		"epoch":     ReasonUnknownAge,
+		"undefined": ReasonUnknownAge,
+	}, reasons)
+	assert.Equal(t, map[string][]string{
+		digests["old"]: {"old"},
+		digests["rc"]:  {"rc"},
+	}, plan.Delete)
+	assert.ElementsMatch(t, []string{"latest", "main", "feature", "shared", "keep-shared", "v1.0.0", "epoch", "undefined"}, plan.Kept())
+
+	// 未设置MaxAge时不限制创建时间
+	plan, err = client.PlanRetention(context.Background(), "app", RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Len(t, plan.Delete, 5)
+	assert.ElementsMatch(t, []string{"latest", "main", "epoch", "undefined"}, plan.Kept())
+}
+
+func TestClient_ApplyRetention(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	digests := pushRetentionImages(reg)
+	policy := RetentionPolicy{KeepLast: 2, KeepSemver: true, MaxAge: 24 * time.Hour}
+
+	plan, err := client.PlanRetention(ctx, "app", policy)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{
+		digests["old"]:    {"old"},
+		digests["shared"]: {"keep-shared", "shared"},
+		digests["rc"]:     {"rc"},
+	}, plan.Delete)
+
+	// 计划生成后rc被打上了新的tag，old被删除
+	reg.pushManifest("app", "hotfix", MediaTypeOCIManifest, reg.bodies[digests["rc"]].body)
+	_, _, err = client.DeleteTag(ctx, "app", "old", nil)
+	assert.Nil(t, err)
+
+	tags, heads := len(reg.tags("app")), reg.count(http.MethodHead, "manifests")
+	report := client.ApplyRetention(ctx, plan, 2)
+	// 每次执行只查询一次所有tag
+	assert.Equal(t, tags, reg.count(http.MethodHead, "manifests")-heads)
+	assert.Equal(t, "app", report.Name)
+	assert.Len(t, report.Results, 3)
+	assert.Equal(t, 2, report.Deleted())
+
+	// 重新查询的tag与计划不一致时跳过
+	skipped := map[string]error{}
+	for _, r := range report.Skipped() {
+		skipped[r.Digest] = r.Err
+		assert.True(t, errors.Is(r.Err, ErrTagsChanged))
+	}
+	assert.Len(t, skipped, 2)
+	assert.Contains(t, skipped, digests["rc"])
+	assert.Contains(t, skipped, digests["old"])
+	assert.Empty(t, report.Failed())
+	assert.Equal(t, []string{"epoch", "feature", "hotfix", "latest", "main", "rc", "undefined", "v1.0.0"}, reg.tags("app"))
+	assert.Equal(t, 2, reg.count(http.MethodDelete, "manifests"))
+	assert.Equal(t, "app: 1 manifests, 2 tags deleted, 2 skipped, 0 failed", report.String())
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	report = client.ApplyRetention(canceled, plan, 0)
+	assert.Len(t, report.Failed(), 3)
+	assert.True(t, errors.Is(report.Results[0].Err, context.Canceled))
+}
+
+func TestClient_PlanRetention_Error(t *testing.T) {
+	reg, _ := newFakeRegistry(t)
+	for i := 0; i < 100; i++ {
+		reg.pushImage("app", fmt.Sprintf("v%d", i), map[string]int{"i": i})
+	}
+	var heads int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			atomic.AddInt32(&heads, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	client, _ := NewClient(Option{URL: srv.URL})
+
+	// 首个查询失败后不再发起新的查询
+	_, err := client.PlanRetention(context.Background(), "app", RetentionPolicy{KeepLast: 1})
+	assert.NotNil(t, err)
+	assert.Less(t, int(atomic.LoadInt32(&heads)), 2*resolveConcurrency)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.inspectTags(canceled, "app", reg.tags("app"), nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -1,6 +1,8 @@
 package dockerhub
 
 import (
+	"time"
+
 	"github.com/docker/distribution/manifest/schema1"
 )
 
@@ -25,23 +27,120 @@ type (
 	// ResponseTag 查询tag接口响应报文
 	ResponseTag struct {
 		Tags []string `json:"tags,omitempty"`
+		Next string   `json:"-"` // 下一页的地址，取自Link header
 	}
 
+	// ResponseManifest 查询manifest接口响应报文，按MediaType区分：
+	// schema1使用Tag、Architecture、FSLayers及History；schema2及OCI image manifest使用Config及Layers；
+	// manifest list及OCI image index使用Manifests
 	ResponseManifest struct {
+		SchemaVersion int    `json:"schemaVersion,omitempty"`
+		MediaType     string `json:"mediaType,omitempty"`
+		Digest        string `json:"-"` // Docker-Content-Digest
+		Raw           []byte `json:"-"` // 原始报文，计算digest或重新推送时使用
+
 		Tag          string            `json:"tag,omitempty"`
 		Architecture string            `json:"architecture,omitempty"`
 		FSLayers     []schema1.FSLayer `json:"fsLayers,omitempty"`
 		History      []schema1.History `json:"history,omitempty"`
+
+		Config      *Descriptor       `json:"config,omitempty"`
+		Layers      []Descriptor      `json:"layers,omitempty"`
+		Manifests   []Descriptor      `json:"manifests,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}
+
+	// Descriptor 引用其他manifest或blob的描述符
+	Descriptor struct {
+		MediaType   string            `json:"mediaType,omitempty"`
+		Digest      string            `json:"digest"`
+		Size        int64             `json:"size"`
+		URLs        []string          `json:"urls,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+		Platform    *Platform         `json:"platform,omitempty"` // 仅manifest list及OCI image index中存在
+	}
+
+	// Platform 镜像运行的平台
+	Platform struct {
+		Architecture string   `json:"architecture"`
+		OS           string   `json:"os"`
+		OSVersion    string   `json:"os.version,omitempty"`
+		OSFeatures   []string `json:"os.features,omitempty"`
+		Variant      string   `json:"variant,omitempty"`
+		Features     []string `json:"features,omitempty"`
+	}
+
+	// Image 镜像详情，由manifest及镜像配置组成
+	Image struct {
+		Digest      string       // manifest的digest，多平台镜像为所选平台的manifest
+		MediaType   string       // manifest的媒体类型
+		Layers      []Descriptor // schema1镜像为空
+		Size        int64        // 各层压缩后的大小之和，schema1镜像为0
+		ImageConfig              // 镜像配置
+	}
+
+	// ImageConfig 镜像配置，schema1镜像取自history中最新一层的v1Compatibility，不含RootFS
+	// https://github.com/opencontainers/image-spec/blob/main/config.md
+	ImageConfig struct {
+		Created      time.Time       `json:"created"`
+		Author       string          `json:"author,omitempty"`
+		Architecture string          `json:"architecture,omitempty"`
+		OS           string          `json:"os,omitempty"`
+		OSVersion    string          `json:"os.version,omitempty"`
+		Variant      string          `json:"variant,omitempty"`
+		Config       ContainerConfig `json:"config"`
+		RootFS       RootFS          `json:"rootfs"`
+	}
+
+	// ContainerConfig 运行容器时使用的默认参数
+	ContainerConfig struct {
+		User         string              `json:"User,omitempty"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"` // 如"8080/tcp"
+		Env          []string            `json:"Env,omitempty"`
+		Entrypoint   []string            `json:"Entrypoint,omitempty"`
+		Cmd          []string            `json:"Cmd,omitempty"`
+		Volumes      map[string]struct{} `json:"Volumes,omitempty"`
+		WorkingDir   string              `json:"WorkingDir,omitempty"`
+		Labels       map[string]string   `json:"Labels,omitempty"`
+		StopSignal   string              `json:"StopSignal,omitempty"`
+	}
+
+	// RootFS 镜像各层解压后的digest，与Image.Layers一一对应
+	RootFS struct {
+		Type    string   `json:"type"`
+		DiffIDs []string `json:"diff_ids"`
 	}
 
 	// ResponseRepository 查询存储库响应报文
 	ResponseRepository struct {
 		Repository []string `json:"repositories,omitempty"`
+		Next       string   `json:"-"` // 下一页的地址，取自Link header
 	}
 
 	ListTagsOption struct {
-		Number int
+		Number int    // 每页数量，为0时由仓库决定
+		Last   string // 从该tag之后开始查询
 	}
+
+	ListRepositoriesOption struct {
+		Number int    // 每页数量，为0时由仓库决定
+		Last   string // 从该存储库之后开始查询
+	}
+)
+
+const (
+	// https://docs.docker.com/registry/spec/manifest-v2-2/#media-types
+	MediaTypeSchema1Manifest       = "application/vnd.docker.distribution.manifest.v1+json"
+	MediaTypeSchema1SignedManifest = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+	MediaTypeSchema2Manifest       = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeManifestList          = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeSchema2Config         = "application/vnd.docker.container.image.v1+json"
+	MediaTypeSchema2Layer          = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+	// https://github.com/opencontainers/image-spec/blob/main/media-types.md
+	MediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex    = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIConfig   = "application/vnd.oci.image.config.v1+json"
+	MediaTypeOCILayer    = "application/vnd.oci.image.layer.v1.tar+gzip"
 )
 
 const (
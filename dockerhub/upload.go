@@ -0,0 +1,223 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jacexh/requests"
+)
+
+// DefaultChunkSize UploadBlob默认的分块大小
+const DefaultChunkSize = 5 << 20
+
+// BlobUpload blob上传会话 https://docs.docker.com/registry/spec/api/#pushing-a-layer
+type BlobUpload struct {
+	client   *Client
+	Name     string
+	Location string // 下一次请求的地址，每次请求后由仓库更新，保存后可通过ResumeBlobUpload续传
+	UUID     string // Docker-Upload-UUID
+	Offset   int64  // 仓库已接收的字节数
+}
+
+// StartBlobUpload 创建上传会话
+func (client *Client) StartBlobUpload(ctx context.Context, name string) (*BlobUpload, *http.Response, error) {
+	res, err := client.upload(ctx, http.MethodPost, client.url("/v2/%s/blobs/uploads/", name), requests.Params{}, http.StatusAccepted)
+	if err != nil {
+		return nil, res, err
+	}
+	upload := &BlobUpload{client: client, Name: name}
+	return upload, res, upload.update(res)
+}
+
+// MountBlob 从同一仓库的其他存储库挂载blob，避免重复上传共享的镜像层。挂载成功时返回true；
+// 仓库不支持或无权访问from时返回false及一个新的上传会话，调用方可继续上传
+func (client *Client) MountBlob(ctx context.Context, name, digest, from string) (bool, *BlobUpload, *http.Response, error) {
+	query := url.Values{"mount": {digest}, "from": {from}}
+	res, err := client.upload(ctx, http.MethodPost, client.url("/v2/%s/blobs/uploads/?%s", name, query.Encode()), requests.Params{}, 0)
+	if err != nil {
+		return false, nil, res, err
+	}
+	switch res.StatusCode {
+	case http.StatusCreated:
+		return true, nil, res, nil
+	case http.StatusAccepted:
+		upload := &BlobUpload{client: client, Name: name}
+		return false, upload, res, upload.update(res)
+	}
+	return false, nil, res, statusError(res, ErrBlobUploadUnknown)
+}
+
+// ResumeBlobUpload 根据保存的Location恢复上传会话，并向仓库查询已接收的字节数
+func (client *Client) ResumeBlobUpload(ctx context.Context, name, location string) (*BlobUpload, *http.Response, error) {
+	upload := &BlobUpload{client: client, Name: name, Location: location}
+	res, err := upload.Status(ctx)
+	if err != nil {
+		return nil, res, err
+	}
+	return upload, res, nil
+}
+
+// UploadBlob 分块上传r中的全部内容并以digest提交，blob已存在时直接返回。chunkSize小于等于0时使用DefaultChunkSize
+func (client *Client) UploadBlob(ctx context.Context, name, digest string, r io.Reader, chunkSize int) (*Descriptor, error) {
+	if d, _, err := client.StatBlob(ctx, name, digest); err == nil {
+		return d, nil
+	} else if !isErrorCode(err, ErrBlobUnknown) {
+		return nil, err
+	}
+
+	upload, _, err := client.StartBlobUpload(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	verifier, err := newDigestVerifier(digest)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, rerr := io.ReadFull(r, buf)
+		if n > 0 {
+			_, _ = verifier.Write(buf[:n])
+			if _, err = upload.UploadChunk(ctx, buf[:n]); err != nil {
+				_, _ = upload.Cancel(ctx)
+				return nil, err
+			}
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			_, _ = upload.Cancel(ctx)
+			return nil, rerr
+		}
+	}
+	if err = verifier.Verify(); err != nil {
+		_, _ = upload.Cancel(ctx)
+		return nil, err
+	}
+	d, _, err := upload.Complete(ctx, digest, nil)
+	return d, err
+}
+
+// UploadChunk 上传一个分块，分块需按顺序上传
+func (upload *BlobUpload) UploadChunk(ctx context.Context, chunk []byte) (*http.Response, error) {
+	res, err := upload.client.upload(ctx, http.MethodPatch, upload.Location, requests.Params{
+		Body: chunk,
+		Headers: requests.Any{
+			"Content-Type":  "application/octet-stream",
+			"Content-Range": fmt.Sprintf("%d-%d", upload.Offset, upload.Offset+int64(len(chunk))-1),
+		},
+	}, http.StatusAccepted)
+	if err != nil {
+		return res, err
+	}
+	return res, upload.update(res)
+}
+
+// Complete 以digest提交上传，last为最后一部分内容（整体上传时为全部内容），可为空
+func (upload *BlobUpload) Complete(ctx context.Context, digest string, last []byte) (*Descriptor, *http.Response, error) {
+	location, err := withQuery(upload.Location, "digest", digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	params := requests.Params{Body: last, Headers: requests.Any{"Content-Type": "application/octet-stream"}}
+	if last == nil {
+		params.Body = []byte{}
+	}
+	res, err := upload.client.upload(ctx, http.MethodPut, location, params, http.StatusCreated)
+	if err != nil {
+		return nil, res, err
+	}
+	d := &Descriptor{Digest: res.Header.Get("Docker-Content-Digest"), Size: upload.Offset + int64(len(last))}
+	if d.Digest == "" {
+		d.Digest = digest
+	}
+	return d, res, nil
+}
+
+// Status 查询仓库已接收的字节数，更新Offset及Location
+func (upload *BlobUpload) Status(ctx context.Context) (*http.Response, error) {
+	res, err := upload.client.upload(ctx, http.MethodGet, upload.Location, requests.Params{}, http.StatusNoContent)
+	if err != nil {
+		return res, err
+	}
+	return res, upload.update(res)
+}
+
+// Cancel 取消上传，仓库将清理已上传的内容
+func (upload *BlobUpload) Cancel(ctx context.Context) (*http.Response, error) {
+	return upload.client.upload(ctx, http.MethodDelete, upload.Location, requests.Params{}, http.StatusNoContent)
+}
+
+// update 根据响应的Location、Docker-Upload-UUID及Range更新上传会话
+func (upload *BlobUpload) update(res *http.Response) error {
+	if location := res.Header.Get("Location"); location != "" {
+		u, err := res.Request.URL.Parse(location)
+		if err != nil {
+			return fmt.Errorf("dockerhub: bad upload location %q: %w", location, err)
+		}
+		upload.Location = u.String()
+	}
+	if upload.Location == "" {
+		return errors.New("dockerhub: missing upload location")
+	}
+	if uuid := res.Header.Get("Docker-Upload-UUID"); uuid != "" {
+		upload.UUID = uuid
+	}
+	// Range: 0-<已接收的最后一个字节的偏移量>，部分仓库带有bytes=前缀
+	if r := res.Header.Get("Range"); r != "" {
+		i := strings.IndexByte(r, '-')
+		if i < 0 || strings.TrimPrefix(r[:i], "bytes=") != "0" {
+			return fmt.Errorf("dockerhub: bad upload range %q", r)
+		}
+		end, err := strconv.ParseInt(r[i+1:], 10, 64)
+		if err != nil || end < 0 {
+			return fmt.Errorf("dockerhub: bad upload range %q", r)
+		}
+		upload.Offset = end + 1
+		if end == 0 && res.Request.Method != http.MethodPatch {
+			// 仓库对未接收任何内容的会话同样返回0-0
+			upload.Offset = 0
+		}
+	}
+	return nil
+}
+
+// upload 发送上传相关的请求并关闭响应报文，expected不为0时响应状态码不一致即返回错误
+func (client *Client) upload(ctx context.Context, method, path string, params requests.Params, expected int) (*http.Response, error) {
+	res, err := client.stream(ctx, method, path, params)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResponse(res)
+	if expected != 0 && res.StatusCode != expected {
+		return res, responseError(res, ErrBlobUploadUnknown)
+	}
+	return res, nil
+}
+
+func withQuery(location, key, value string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// isErrorCode err是否为指定错误码的仓库错误
+func isErrorCode(err error, code ErrorCode) bool {
+	var e Error
+	return errors.As(err, &e) && e.Code == code
+}
@@ -0,0 +1,141 @@
+package dockerhub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlobUpload_Update(t *testing.T) {
+	req := func(method string) *http.Request {
+		r, _ := http.NewRequest(method, "https://registry.example.com/v2/app/blobs/uploads/", nil)
+		return r
+	}
+	cases := []struct {
+		method string
+		rng    string
+		offset int64
+		bad    bool
+	}{
+		{http.MethodPost, "0-0", 0, false},
+		{http.MethodGet, "0-0", 0, false},
+		{http.MethodPatch, "0-0", 1, false},
+		{http.MethodPatch, "0-99", 100, false},
+		{http.MethodGet, "bytes=0-99", 100, false},
+		{http.MethodPatch, "99", 0, true},
+		{http.MethodPatch, "0-", 0, true},
+		{http.MethodPatch, "0-abc", 0, true},
+		{http.MethodPatch, "10-99", 0, true},
+		{http.MethodPatch, "0--1", 0, true},
+	}
+	for _, c := range cases {
+		upload := new(BlobUpload)
+		err := upload.update(&http.Response{
+			Header:  http.Header{"Location": {"/v2/app/blobs/uploads/1?_state=x"}, "Range": {c.rng}},
+			Request: req(c.method),
+		})
+		if c.bad {
+			assert.NotNil(t, err, c.rng)
+			continue
+		}
+		assert.Nil(t, err, c.rng)
+		assert.Equal(t, c.offset, upload.Offset, c.method+" "+c.rng)
+		assert.Equal(t, "https://registry.example.com/v2/app/blobs/uploads/1?_state=x", upload.Location)
+	}
+
+	err := new(BlobUpload).update(&http.Response{Header: http.Header{}, Request: req(http.MethodPost)})
+	assert.NotNil(t, err)
+}
+
+func TestClient_BlobUpload(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("chunk"), 100)
+	digest := Digest(data)
+
+	upload, _, err := client.StartBlobUpload(ctx, "app")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, upload.UUID)
+	assert.EqualValues(t, 0, upload.Offset)
+
+	_, err = upload.UploadChunk(ctx, data[:200])
+	assert.Nil(t, err)
+	assert.EqualValues(t, 200, upload.Offset)
+
+	// 根据保存的Location续传
+	resumed, _, err := client.ResumeBlobUpload(ctx, "app", upload.Location)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 200, resumed.Offset)
+	assert.Equal(t, upload.UUID, resumed.UUID)
+
+	_, err = resumed.UploadChunk(ctx, data[200:400])
+	assert.Nil(t, err)
+	d, _, err := resumed.Complete(ctx, digest, data[400:])
+	assert.Nil(t, err)
+	assert.Equal(t, &Descriptor{Digest: digest, Size: int64(len(data))}, d)
+	assert.Equal(t, data, reg.blobs[digest])
+
+	// 已完成的会话不能续传
+	_, _, err = client.ResumeBlobUpload(ctx, "app", upload.Location)
+	assert.True(t, isErrorCode(err, ErrBlobUploadUnknown))
+
+	// 空会话查询进度时仓库返回0-0
+	upload, _, err = client.StartBlobUpload(ctx, "app")
+	assert.Nil(t, err)
+	_, err = upload.Status(ctx)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, upload.Offset)
+	_, err = upload.Cancel(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, reg.uploads)
+}
+
+func TestClient_MountBlob(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	reg.pushImage("base", "latest", map[string]string{})
+	layer := reg.pushBlob([]byte("shared layer"))
+
+	mounted, upload, _, err := client.MountBlob(ctx, "app", layer, "base")
+	assert.Nil(t, err)
+	assert.True(t, mounted)
+	assert.Nil(t, upload)
+
+	// 无法挂载时返回新的上传会话
+	mounted, upload, _, err = client.MountBlob(ctx, "app", layer, "unknown")
+	assert.Nil(t, err)
+	assert.False(t, mounted)
+	assert.NotNil(t, upload)
+	u, _ := url.Parse(upload.Location)
+	assert.Equal(t, "/v2/app/blobs/uploads/"+upload.UUID, u.Path)
+}
+
+func TestClient_UploadBlob(t *testing.T) {
+	reg, client := newFakeRegistry(t)
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	digest := Digest(data)
+
+	d, err := client.UploadBlob(ctx, "app", digest, bytes.NewReader(data), 3000)
+	assert.Nil(t, err)
+	assert.Equal(t, &Descriptor{Digest: digest, Size: int64(len(data))}, d)
+	assert.Equal(t, data, reg.blobs[digest])
+	assert.Equal(t, 4, reg.count(http.MethodPatch, "uploads"))
+
+	// blob已存在时不再上传
+	_, err = client.UploadBlob(ctx, "app", digest, bytes.NewReader(data), 3000)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, reg.count(http.MethodPost, "uploads"))
+
+	// 内容与digest不符时取消上传
+	other := Digest([]byte("other"))
+	_, err = client.UploadBlob(ctx, "app", other, bytes.NewReader(data), 0)
+	assert.True(t, errors.Is(err, ErrDigestMismatch))
+	assert.Nil(t, reg.blobs[other])
+	assert.Empty(t, reg.uploads)
+}
@@ -6,4 +6,5 @@ require (
 	github.com/jacexh/requests v0.1.6
 	github.com/stretchr/testify v1.6.1
 	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
+	golang.org/x/time v0.0.0-20210723032227-1f47c861a9ac
 )